@@ -0,0 +1,370 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"net/http"
+	"testing"
+)
+
+// courseBody tiene los campos que exigen crear y actualizar un curso.
+const courseBody = `"name":"Go avanzado","start_date":"2099-01-01","end_date":"2099-06-01"`
+
+func TestCourseCreate(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor creates and teaches it", as: "teacher", method: "POST", path: "/courses",
+			body: `{` + courseBody + `,"capacity":20}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				var c domain.Course
+				decodeData(t, res, &c)
+				if c.Name != "Go avanzado" || c.Capacity != 20 || c.StartDate.Format("2006-01-02") != "2099-01-01" {
+					t.Errorf("created course = %+v", c)
+				}
+				if ok, _ := api.store.courseRepo.IsInstructor(context.Background(), c.ID, "teacher"); !ok {
+					t.Error("the instructor who created the course does not teach it")
+				}
+			},
+		},
+		{
+			name: "admin creates without teaching it", as: "admin", method: "POST", path: "/courses",
+			body: `{` + courseBody + `}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				var c domain.Course
+				decodeData(t, res, &c)
+				if ok, _ := api.store.courseRepo.IsInstructor(context.Background(), c.ID, "admin"); ok {
+					t.Error("admin was assigned as instructor")
+				}
+			},
+		},
+		{
+			name: "students cannot create courses", as: "student", method: "POST", path: "/courses",
+			body: `{` + courseBody + `}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "malformed body", as: "teacher", method: "POST", path: "/courses",
+			body: `{"name":`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "invalid fields", as: "teacher", method: "POST", path: "/courses",
+			body: `{"start_date":"01/02/2099","end_date":"2099-06-01","capacity":-1}`, status: http.StatusBadRequest, code: "validation_failed",
+		},
+		{
+			name: "enrollment opens after it closes", as: "teacher", method: "POST", path: "/courses",
+			body: `{` + courseBody + `,"enrollment_open":"2099-02-01"}`, status: http.StatusBadRequest, code: "invalid_enrollment_window",
+		},
+	})
+}
+
+func TestCourseGet(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "with its instructors", as: "student", method: "GET", path: "/courses/go", status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var c domain.Course
+				decodeData(t, res, &c)
+				if c.ID != "go" || len(c.Instructors) != 1 || c.Instructors[0].ID != "teacher" {
+					t.Errorf("course = %+v, want go taught by teacher", c)
+				}
+			},
+		},
+		{
+			name: "missing course", as: "student", method: "GET", path: "/courses/missing", status: http.StatusNotFound, code: "course_not_found",
+		},
+		{
+			name: "v1 error format", as: "student", method: "GET", path: "/courses/missing",
+			header: http.Header{"Api-Version": {"1"}}, status: http.StatusNotFound, code: "course_not_found",
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var body map[string]json.RawMessage
+				if err := json.Unmarshal(res.raw, &body); err != nil {
+					t.Fatal(err)
+				}
+				if _, ok := body["err"]; !ok || string(body["data"]) != "null" {
+					t.Errorf("v1 body = %s, want err and a null data", res.raw)
+				}
+			},
+		},
+		{
+			name: "without a token", method: "GET", path: "/courses/go", status: http.StatusUnauthorized, code: "token_required",
+		},
+	})
+}
+
+func TestCourseGetAll(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "every course", as: "student", method: "GET", path: "/courses", status: http.StatusOK,
+			check: wantTotal(2),
+		},
+		{
+			name: "by name", as: "student", method: "GET", path: "/courses?name=RU", status: http.StatusOK,
+			check: wantTotal(1),
+		},
+		{
+			name: "by instructor", as: "student", method: "GET", path: "/courses?instructor_id=teacher", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				wantTotal(1)(t, api, res)
+				var courses []domain.Course
+				decodeData(t, res, &courses)
+				if len(courses) != 1 || courses[0].ID != "go" {
+					t.Errorf("courses = %+v, want only go", courses)
+				}
+			},
+		},
+		{
+			name: "second page", as: "student", method: "GET", path: "/courses?limit=1&page=2", status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var courses []domain.Course
+				decodeData(t, res, &courses)
+				if len(courses) != 1 || res.Meta.Page != 2 {
+					t.Errorf("page %d has %d courses, want page 2 with 1", res.Meta.Page, len(courses))
+				}
+			},
+		},
+	})
+}
+
+func TestCourseGetTaught(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor", as: "student", method: "GET", path: "/users/teacher/courses-taught", status: http.StatusOK,
+			check: wantTotal(1),
+		},
+		{
+			name: "user who teaches nothing", as: "student", method: "GET", path: "/users/student/courses-taught", status: http.StatusOK,
+			check: wantTotal(0),
+		},
+		{
+			name: "without a token", method: "GET", path: "/users/teacher/courses-taught", status: http.StatusUnauthorized, code: "token_required",
+		},
+	})
+}
+
+func TestCourseUpdate(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor of the course", as: "teacher", method: "PATCH", path: "/courses/go",
+			body: `{` + courseBody + `}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				c, _ := api.store.courseRepo.Get(context.Background(), "go")
+				if c.Name != "Go avanzado" || c.StartDate.Format("2006-01-02") != "2099-01-01" || c.Capacity != 1 {
+					t.Errorf("after update course = %+v", c)
+				}
+			},
+		},
+		{
+			name: "more seats promote the waitlist", as: "admin", method: "PATCH", path: "/courses/go",
+			body: `{` + courseBody + `,"capacity":2}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				e, _ := api.store.enrollRepo.Get(context.Background(), "e2")
+				if e.Status != domain.EnrollmentPending {
+					t.Errorf("waitlisted enrollment is %s, want pending", e.Status)
+				}
+			},
+		},
+		{
+			name: "only the capacity", as: "admin", method: "PATCH", path: "/courses/go",
+			body: `{"capacity":3}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				c, _ := api.store.courseRepo.Get(context.Background(), "go")
+				if c.Capacity != 3 || c.Name != "Go" {
+					t.Errorf("after update course = %+v, want capacity 3 and the name unchanged", c)
+				}
+			},
+		},
+		{
+			name: "only the enrollment window", as: "teacher", method: "PATCH", path: "/courses/go",
+			body: `{"enrollment_open":"2000-01-01"}`, status: http.StatusOK,
+		},
+		{
+			name: "empty name", as: "admin", method: "PATCH", path: "/courses/go",
+			body: `{"name":""}`, status: http.StatusBadRequest, code: "validation_failed",
+		},
+		{
+			name: "instructor of another course", as: "teacher", method: "PATCH", path: "/courses/rust",
+			body: `{` + courseBody + `}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "students cannot update courses", as: "student", method: "PATCH", path: "/courses/go",
+			body: `{` + courseBody + `}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "malformed body", as: "admin", method: "PATCH", path: "/courses/go",
+			body: `nope`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "invalid fields", as: "admin", method: "PATCH", path: "/courses/go",
+			body: `{"name":"Go","start_date":"2099-13-01","end_date":"2099-06-01"}`, status: http.StatusBadRequest, code: "validation_failed",
+		},
+		{
+			name: "enrollment opens after the course starts", as: "admin", method: "PATCH", path: "/courses/go",
+			body: `{` + courseBody + `,"enrollment_open":"2099-02-01"}`, status: http.StatusBadRequest, code: "invalid_enrollment_window",
+		},
+		{
+			name: "missing course", as: "admin", method: "PATCH", path: "/courses/missing",
+			body: `{` + courseBody + `}`, status: http.StatusNotFound, code: "course_not_found",
+		},
+	})
+}
+
+func TestCourseDelete(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "admin", as: "admin", method: "DELETE", path: "/courses/rust", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if rec := api.do(t, "admin", "GET", "/courses/rust", "", nil); rec.Code != http.StatusNotFound {
+					t.Errorf("GET after delete = %d, want 404", rec.Code)
+				}
+			},
+		},
+		{
+			name: "instructors cannot delete courses", as: "teacher", method: "DELETE", path: "/courses/go", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "missing course", as: "admin", method: "DELETE", path: "/courses/missing", status: http.StatusNotFound, code: "course_not_found",
+		},
+	})
+}
+
+// rustBeforeGo hace de rust un prerrequisito de go.
+func rustBeforeGo(t *testing.T, api *testAPI) {
+	t.Helper()
+	if err := api.store.courseRepo.AddPrerequisite(context.Background(), "go", "rust"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCourseAddPrerequisite(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor of the course", as: "teacher", method: "POST", path: "/courses/go/prerequisites",
+			body: `{"prerequisite_id":"rust"}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				c, _ := api.store.courseRepo.Get(context.Background(), "go")
+				if len(c.Prerequisites) != 1 || c.Prerequisites[0].ID != "rust" {
+					t.Errorf("prerequisites = %+v, want rust", c.Prerequisites)
+				}
+			},
+		},
+		{
+			name: "itself", as: "admin", method: "POST", path: "/courses/go/prerequisites",
+			body: `{"prerequisite_id":"go"}`, status: http.StatusConflict, code: "prerequisite_cycle",
+		},
+		{
+			name: "cycle", as: "admin", method: "POST", path: "/courses/rust/prerequisites",
+			body: `{"prerequisite_id":"go"}`, setup: rustBeforeGo, status: http.StatusConflict, code: "prerequisite_cycle",
+		},
+		{
+			name: "instructor of another course", as: "teacher", method: "POST", path: "/courses/rust/prerequisites",
+			body: `{"prerequisite_id":"go"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "without prerequisite_id", as: "admin", method: "POST", path: "/courses/go/prerequisites",
+			body: `{}`, status: http.StatusBadRequest, code: "prerequisite_id_required",
+		},
+		{
+			name: "malformed body", as: "admin", method: "POST", path: "/courses/go/prerequisites",
+			body: `"rust"`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "missing prerequisite", as: "admin", method: "POST", path: "/courses/go/prerequisites",
+			body: `{"prerequisite_id":"missing"}`, status: http.StatusNotFound, code: "course_not_found",
+		},
+	})
+}
+
+func TestCourseRemovePrerequisite(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor of the course", as: "teacher", method: "DELETE", path: "/courses/go/prerequisites/rust",
+			setup: rustBeforeGo, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if c, _ := api.store.courseRepo.Get(context.Background(), "go"); len(c.Prerequisites) != 0 {
+					t.Errorf("prerequisites = %+v, want none", c.Prerequisites)
+				}
+			},
+		},
+		{
+			name: "students cannot remove prerequisites", as: "student", method: "DELETE", path: "/courses/go/prerequisites/rust",
+			setup: rustBeforeGo, status: http.StatusForbidden, code: "forbidden",
+		},
+	})
+}
+
+func TestCourseGetPrerequisites(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "direct prerequisites", as: "student", method: "GET", path: "/courses/go/prerequisites",
+			setup: rustBeforeGo, status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var courses []domain.Course
+				decodeData(t, res, &courses)
+				if len(courses) != 1 || courses[0].ID != "rust" {
+					t.Errorf("prerequisites = %+v, want rust", courses)
+				}
+			},
+		},
+		{
+			name: "none", as: "student", method: "GET", path: "/courses/rust/prerequisites", status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				if string(res.Data) != "[]" {
+					t.Errorf("data = %s, want []", res.Data)
+				}
+			},
+		},
+		{
+			name: "missing course", as: "student", method: "GET", path: "/courses/missing/prerequisites", status: http.StatusNotFound, code: "course_not_found",
+		},
+	})
+}
+
+func TestCourseAssignInstructor(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "admin", as: "admin", method: "POST", path: "/courses/rust/instructors",
+			body: `{"user_id":"teacher"}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if ok, _ := api.store.courseRepo.IsInstructor(context.Background(), "rust", "teacher"); !ok {
+					t.Error("teacher does not teach rust")
+				}
+			},
+		},
+		{
+			name: "instructor of another course", as: "teacher", method: "POST", path: "/courses/rust/instructors",
+			body: `{"user_id":"teacher"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "a student", as: "admin", method: "POST", path: "/courses/go/instructors",
+			body: `{"user_id":"student"}`, status: http.StatusBadRequest, code: "cannot_teach",
+		},
+		{
+			name: "missing user", as: "admin", method: "POST", path: "/courses/go/instructors",
+			body: `{"user_id":"missing"}`, status: http.StatusNotFound, code: "user_not_found",
+		},
+		{
+			name: "without user_id", as: "admin", method: "POST", path: "/courses/go/instructors",
+			body: `{}`, status: http.StatusBadRequest, code: "user_id_required",
+		},
+		{
+			name: "malformed body", as: "admin", method: "POST", path: "/courses/go/instructors",
+			body: `{"user_id":1}`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+	})
+}
+
+func TestCourseUnassignInstructor(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor of the course", as: "teacher", method: "DELETE", path: "/courses/go/instructors/teacher", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if ok, _ := api.store.courseRepo.IsInstructor(context.Background(), "go", "teacher"); ok {
+					t.Error("teacher still teaches go")
+				}
+			},
+		},
+		{
+			name: "students cannot unassign instructors", as: "student", method: "DELETE", path: "/courses/go/instructors/teacher", status: http.StatusForbidden, code: "forbidden",
+		},
+	})
+}
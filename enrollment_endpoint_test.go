@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// wantStatus comprueba que la inscripción id quedó en status.
+func wantStatus(id string, status domain.EnrollmentStatus) func(t *testing.T, api *testAPI, res apiResponse) {
+	return func(t *testing.T, api *testAPI, _ apiResponse) {
+		t.Helper()
+		e, err := api.store.enrollRepo.Get(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Status != status {
+			t.Errorf("enrollment %s is %s, want %s", id, e.Status, status)
+		}
+	}
+}
+
+func TestEnrollmentCreate(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "student enrolls themself", as: "student", method: "POST", path: "/enrollments",
+			body: `{"course_id":"rust"}`, status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var e domain.Enrollment
+				decodeData(t, res, &e)
+				if e.UserID != "student" || e.CourseID != "rust" || string(e.Status) != "pending" {
+					t.Errorf("enrollment = %+v", e)
+				}
+			},
+		},
+		{
+			name: "full course puts them on the waitlist", as: "teacher", method: "POST", path: "/enrollments",
+			body: `{"course_id":"go"}`, status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var e domain.Enrollment
+				decodeData(t, res, &e)
+				if string(e.Status) != "waitlisted" || e.WaitlistPosition == nil || *e.WaitlistPosition != 2 {
+					t.Errorf("enrollment = %+v, want waitlisted at position 2", e)
+				}
+			},
+		},
+		{
+			name: "admin enrolls another user", as: "admin", method: "POST", path: "/enrollments",
+			body: `{"course_id":"rust","user_id":"other"}`, status: http.StatusOK,
+		},
+		{
+			name: "student enrolls another user", as: "student", method: "POST", path: "/enrollments",
+			body: `{"course_id":"rust","user_id":"other"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "already enrolled", as: "student", method: "POST", path: "/enrollments",
+			body: `{"course_id":"go"}`, status: http.StatusConflict, code: "already_enrolled",
+		},
+		{
+			name: "missing prerequisites", as: "student", method: "POST", path: "/enrollments",
+			body: `{"course_id":"rust"}`, status: http.StatusConflict, code: "missing_prerequisites",
+			setup: func(t *testing.T, api *testAPI) {
+				if err := api.store.courseRepo.AddPrerequisite(context.Background(), "rust", "go"); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "finished course", as: "student", method: "POST", path: "/enrollments",
+			body: `{"course_id":"past"}`, status: http.StatusConflict, code: "course_finished",
+			setup: func(t *testing.T, api *testAPI) {
+				now := time.Now()
+				past := &domain.Course{ID: "past", Name: "Past", StartDate: now.AddDate(0, -2, 0), EndDate: now.AddDate(0, 0, -1)}
+				if err := api.store.courseRepo.Create(context.Background(), past); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "missing course", as: "student", method: "POST", path: "/enrollments",
+			body: `{"course_id":"missing"}`, status: http.StatusNotFound, code: "course_not_found",
+		},
+		{
+			name: "missing user", as: "admin", method: "POST", path: "/enrollments",
+			body: `{"course_id":"rust","user_id":"missing"}`, status: http.StatusNotFound, code: "user_not_found",
+		},
+		{
+			name: "without course_id", as: "student", method: "POST", path: "/enrollments",
+			body: `{}`, status: http.StatusBadRequest, code: "course_id_required",
+		},
+		{
+			name: "malformed body", as: "student", method: "POST", path: "/enrollments",
+			body: `{`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "without a token", method: "POST", path: "/enrollments",
+			body: `{"course_id":"rust"}`, status: http.StatusUnauthorized, code: "token_required",
+		},
+	})
+}
+
+func TestEnrollmentGet(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "own enrollment", as: "student", method: "GET", path: "/enrollments/e1", status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var e domain.Enrollment
+				decodeData(t, res, &e)
+				if e.ID != "e1" || e.Course == nil || e.Course.ID != "go" {
+					t.Errorf("enrollment = %+v, want e1 with its course", e)
+				}
+			},
+		},
+		{
+			name: "instructor of the course", as: "teacher", method: "GET", path: "/enrollments/e1", status: http.StatusOK,
+		},
+		{
+			name: "another user's enrollment looks missing", as: "other", method: "GET", path: "/enrollments/e1", status: http.StatusNotFound, code: "enrollment_not_found",
+		},
+		{
+			name: "missing enrollment", as: "admin", method: "GET", path: "/enrollments/missing", status: http.StatusNotFound, code: "enrollment_not_found",
+		},
+		{
+			name: "v1 error format", as: "admin", method: "GET", path: "/enrollments/missing",
+			header: http.Header{"Api-Version": {"1"}}, status: http.StatusNotFound, check: wantLegacyError,
+		},
+	})
+}
+
+func TestEnrollmentGetAll(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "students see their own", as: "student", method: "GET", path: "/enrollments", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				wantTotal(1)(t, api, res)
+				var enrollments []domain.Enrollment
+				decodeData(t, res, &enrollments)
+				if len(enrollments) != 1 || enrollments[0].ID != "e1" {
+					t.Errorf("enrollments = %+v, want only e1", enrollments)
+				}
+			},
+		},
+		{
+			name: "instructor lists their course", as: "teacher", method: "GET", path: "/enrollments?course_id=go", status: http.StatusOK,
+			check: wantTotal(2),
+		},
+		{
+			name: "admin lists everything", as: "admin", method: "GET", path: "/enrollments", status: http.StatusOK,
+			check: wantTotal(2),
+		},
+		{
+			name: "by status", as: "admin", method: "GET", path: "/enrollments?status=waitlisted", status: http.StatusOK,
+			check: wantTotal(1),
+		},
+		{
+			name: "student lists a course", as: "student", method: "GET", path: "/enrollments?course_id=go", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "student lists another user", as: "student", method: "GET", path: "/enrollments?user_id=other", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "unknown status", as: "admin", method: "GET", path: "/enrollments?status=done", status: http.StatusBadRequest, code: "invalid_status",
+		},
+	})
+}
+
+func TestEnrollmentUpdate(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor of the course", as: "teacher", method: "PATCH", path: "/enrollments/e1",
+			body: `{"status":"active"}`, status: http.StatusOK, check: wantStatus("e1", domain.EnrollmentActive),
+		},
+		{
+			name: "status code instead of name", as: "admin", method: "PATCH", path: "/enrollments/e1",
+			body: `{"status":"R"}`, status: http.StatusOK, check: wantStatus("e1", domain.EnrollmentRejected),
+		},
+		{
+			name: "students cannot change the status", as: "student", method: "PATCH", path: "/enrollments/e1",
+			body: `{"status":"active"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "transition not allowed", as: "teacher", method: "PATCH", path: "/enrollments/e1",
+			body: `{"status":"completed"}`, status: http.StatusConflict, code: "invalid_transition",
+		},
+		{
+			name: "unknown status", as: "teacher", method: "PATCH", path: "/enrollments/e1",
+			body: `{"status":"done"}`, status: http.StatusBadRequest, code: "invalid_status",
+		},
+		{
+			name: "without status", as: "teacher", method: "PATCH", path: "/enrollments/e1",
+			body: `{}`, status: http.StatusBadRequest, code: "status_required",
+		},
+		{
+			name: "malformed body", as: "teacher", method: "PATCH", path: "/enrollments/e1",
+			body: `{"status":true}`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "missing enrollment", as: "admin", method: "PATCH", path: "/enrollments/missing",
+			body: `{"status":"active"}`, status: http.StatusNotFound, code: "enrollment_not_found",
+		},
+	})
+}
+
+func TestEnrollmentTransition(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "withdrawing frees the seat", as: "teacher", method: "POST", path: "/enrollments/e1/transition",
+			body: `{"status":"withdrawn"}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				var e domain.Enrollment
+				decodeData(t, res, &e)
+				if e.ID != "e1" || string(e.Status) != "withdrawn" {
+					t.Errorf("enrollment = %+v, want e1 withdrawn", e)
+				}
+				wantStatus("e2", domain.EnrollmentPending)(t, api, res)
+			},
+		},
+		{
+			name: "students cannot change the status", as: "other", method: "POST", path: "/enrollments/e2/transition",
+			body: `{"status":"withdrawn"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "another user's enrollment looks missing", as: "student", method: "POST", path: "/enrollments/e2/transition",
+			body: `{"status":"withdrawn"}`, status: http.StatusNotFound, code: "enrollment_not_found",
+		},
+		{
+			name: "transition not allowed", as: "teacher", method: "POST", path: "/enrollments/e2/transition",
+			body: `{"status":"active"}`, status: http.StatusConflict, code: "invalid_transition",
+		},
+		{
+			name: "without status", as: "teacher", method: "POST", path: "/enrollments/e1/transition",
+			body: `{"status":""}`, status: http.StatusBadRequest, code: "status_required",
+		},
+		{
+			name: "malformed body", as: "teacher", method: "POST", path: "/enrollments/e1/transition",
+			body: `withdrawn`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+	})
+}
+
+func TestEnrollmentDelete(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "admin", as: "admin", method: "DELETE", path: "/enrollments/e1", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				if _, err := api.store.enrollRepo.Get(context.Background(), "e1"); !errors.Is(err, enrollment.ErrNotFound) {
+					t.Errorf("Get after delete error = %v, want ErrNotFound", err)
+				}
+				wantStatus("e2", domain.EnrollmentPending)(t, api, res)
+			},
+		},
+		{
+			name: "owners cannot delete", as: "student", method: "DELETE", path: "/enrollments/e1", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "missing enrollment", as: "admin", method: "DELETE", path: "/enrollments/missing", status: http.StatusNotFound, code: "enrollment_not_found",
+		},
+	})
+}
+
+func TestEnrollmentWaitlist(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "instructor of the course", as: "teacher", method: "GET", path: "/courses/go/waitlist", status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var enrollments []domain.Enrollment
+				decodeData(t, res, &enrollments)
+				if len(enrollments) != 1 || enrollments[0].ID != "e2" || enrollments[0].WaitlistPosition == nil || *enrollments[0].WaitlistPosition != 1 {
+					t.Errorf("waitlist = %+v, want e2 at position 1", enrollments)
+				}
+			},
+		},
+		{
+			name: "students cannot see it", as: "student", method: "GET", path: "/courses/go/waitlist", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "missing course", as: "admin", method: "GET", path: "/courses/missing/waitlist", status: http.StatusNotFound, code: "course_not_found",
+		},
+	})
+}
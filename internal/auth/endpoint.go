@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"encoding/json"
+	"github.com/raminpz/gocourse_web/internal/policy"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/i18n"
+	"github.com/raminpz/gocourse_web/pkg/response"
+	"net/http"
+	"strings"
+)
+
+type (
+	Controller func(w http.ResponseWriter, r *http.Request)
+	Endpoints  struct {
+		Login   Controller
+		Refresh Controller
+		Logout  Controller
+	}
+
+	LoginReq struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	RefreshReq struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+)
+
+func MakeEndpoints(s Service) Endpoints {
+	return Endpoints{
+		Login:   makeLoginEndpoint(s),
+		Refresh: makeRefreshEndpoint(s),
+		Logout:  makeLogoutEndpoint(s),
+	}
+}
+
+// Middleware exige un access token válido en el header Authorization y deja
+// al usuario autenticado en el contexto de la petición como policy.Actor.
+func Middleware(s Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || token == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				response.Error(w, r, ErrTokenRequired)
+				return
+			}
+
+			u, err := s.Authenticate(r.Context(), token)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				response.Error(w, r, err)
+				return
+			}
+
+			ctx := policy.WithActor(r.Context(), policy.Actor{ID: u.ID, Role: u.Role})
+			if u.Locale != "" {
+				ctx = i18n.WithLocale(ctx, u.Locale)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OptionalMiddleware identifica al usuario si la petición trae un token
+// válido, pero deja pasar también a los visitantes anónimos.
+func OptionalMiddleware(s Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && token != "" {
+				if u, err := s.Authenticate(r.Context(), token); err == nil {
+					ctx := policy.WithActor(r.Context(), policy.Actor{ID: u.ID, Role: u.Role})
+					if u.Locale != "" {
+						ctx = i18n.WithLocale(ctx, u.Locale)
+					}
+					r = r.WithContext(ctx)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func makeLoginEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req LoginReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
+			return
+		}
+		if req.Email == "" || req.Password == "" {
+			response.Error(w, r, apperr.Validation("credentials_required", "email and password are required"))
+			return
+		}
+
+		tokens, err := s.Login(r.Context(), req.Email, req.Password)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK(tokens))
+	}
+}
+
+func makeRefreshEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RefreshReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
+			return
+		}
+		if req.RefreshToken == "" {
+			response.Error(w, r, apperr.Validation("refresh_token_required", "refresh_token is required"))
+			return
+		}
+
+		tokens, err := s.Refresh(r.Context(), req.RefreshToken)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK(tokens))
+	}
+}
+
+func makeLogoutEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RefreshReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
+			return
+		}
+		if req.RefreshToken == "" {
+			response.Error(w, r, apperr.Validation("refresh_token_required", "refresh_token is required"))
+			return
+		}
+
+		if err := s.Logout(r.Context(), req.RefreshToken); err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK("success"))
+	}
+}
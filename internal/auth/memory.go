@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// memoryRepo guarda los refresh tokens en memoria y devuelve los mismos
+// errores que repo.
+type memoryRepo struct {
+	log    *slog.Logger
+	mu     sync.RWMutex
+	tokens map[string]domain.RefreshToken
+}
+
+func NewMemoryRepo(logger *slog.Logger) Repository {
+	return &memoryRepo{
+		log:    logger,
+		tokens: make(map[string]domain.RefreshToken),
+	}
+}
+
+func (r *memoryRepo) Create(ctx context.Context, token *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.insert(token); err != nil {
+		r.log.ErrorContext(ctx, "error creating refresh token", "error", err)
+		return err
+	}
+	return nil
+}
+
+func (r *memoryRepo) GetByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, ErrInvalidToken
+}
+
+// Rotate revoca old y guarda next; si old ya fue revocado no se guarda nada.
+func (r *memoryRepo) Rotate(ctx context.Context, old, next *domain.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.tokens[old.ID]
+	if !ok || current.RevokedAt != nil {
+		return ErrInvalidToken
+	}
+	if err := r.insert(next); err != nil {
+		return err
+	}
+	now := time.Now()
+	replacedBy := next.ID
+	current.RevokedAt = &now
+	current.ReplacedBy = &replacedBy
+	r.tokens[old.ID] = current
+	return nil
+}
+
+func (r *memoryRepo) Revoke(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if token, ok := r.tokens[id]; ok && token.RevokedAt == nil {
+		now := time.Now()
+		token.RevokedAt = &now
+		r.tokens[id] = token
+	}
+	return nil
+}
+
+func (r *memoryRepo) RevokeAll(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.tokens {
+		if token.UserID == userID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
+
+// insert guarda token respetando la clave primaria y el índice único de
+// token_hash. Debe llamarse con r.mu tomado.
+func (r *memoryRepo) insert(token *domain.RefreshToken) error {
+	if token.ID == "" {
+		token.ID = uuid.New().String()
+	}
+	if _, ok := r.tokens[token.ID]; ok {
+		return apperr.DB(gorm.ErrDuplicatedKey, ErrInvalidToken)
+	}
+	for _, other := range r.tokens {
+		if other.TokenHash == token.TokenHash {
+			return apperr.DB(gorm.ErrDuplicatedKey, ErrInvalidToken)
+		}
+	}
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+	r.tokens[token.ID] = *token
+	return nil
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"log/slog"
+	"time"
+)
+
+type (
+	Repository interface {
+		Create(ctx context.Context, token *domain.RefreshToken) error
+		GetByHash(ctx context.Context, hash string) (*domain.RefreshToken, error)
+		Rotate(ctx context.Context, old, next *domain.RefreshToken) error
+		Revoke(ctx context.Context, id string) error
+		RevokeAll(ctx context.Context, userID string) error
+	}
+
+	repo struct {
+		db  *gorm.DB
+		log *slog.Logger
+	}
+)
+
+func NewRepo(db *gorm.DB, logger *slog.Logger) Repository {
+	return &repo{
+		db:  db,
+		log: logger,
+	}
+}
+
+func (r *repo) Create(ctx context.Context, token *domain.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(token).Error; err != nil {
+		r.log.ErrorContext(ctx, "error creating refresh token", "error", err)
+		return apperr.DB(err, ErrInvalidToken)
+	}
+	return nil
+}
+
+// GetByHash devuelve ErrInvalidToken si no hay un token con ese hash.
+func (r *repo) GetByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	result := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token)
+	if result.Error != nil {
+		return nil, apperr.DB(result.Error, ErrInvalidToken)
+	}
+	return &token, nil
+}
+
+// Rotate revoca old y guarda next en la misma transacción. Si old ya fue
+// revocado por otra petición concurrente no se emite el nuevo token.
+func (r *repo) Rotate(ctx context.Context, old, next *domain.RefreshToken) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&domain.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":  time.Now(),
+				"replaced_by": next.ID,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvalidToken
+		}
+		return nil
+	})
+	return apperr.DB(err, ErrInvalidToken)
+}
+
+func (r *repo) Revoke(ctx context.Context, id string) error {
+	err := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+	return apperr.DB(err, ErrInvalidToken)
+}
+
+func (r *repo) RevokeAll(ctx context.Context, userID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	return apperr.DB(err, ErrInvalidToken)
+}
@@ -0,0 +1,128 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"github.com/raminpz/gocourse_web/internal/auth"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/testdb"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRepository(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	t.Run("memory", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) auth.Repository {
+			return auth.NewMemoryRepo(logger)
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) auth.Repository {
+			return auth.NewRepo(testdb.Open(t), logger)
+		})
+	})
+}
+
+// RepositorySuite comprueba el contrato de auth.Repository; newRepo devuelve
+// un repositorio vacío en cada llamada.
+func RepositorySuite(t *testing.T, newRepo func(t *testing.T) auth.Repository) {
+	ctx := context.Background()
+
+	t.Run("create and get by hash", func(t *testing.T) {
+		repo := newRepo(t)
+		token := createToken(t, repo, "user-1", "hash-1")
+		if token.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+
+		got, err := repo.GetByHash(ctx, "hash-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ID != token.ID || got.UserID != "user-1" || got.RevokedAt != nil || got.ReplacedBy != nil {
+			t.Errorf("GetByHash = %+v", got)
+		}
+		if _, err := repo.GetByHash(ctx, "missing"); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("GetByHash(missing) error = %v, want ErrInvalidToken", err)
+		}
+
+		duplicate := &domain.RefreshToken{UserID: "user-2", TokenHash: "hash-1", ExpiresAt: token.ExpiresAt}
+		if err := repo.Create(ctx, duplicate); apperr.From(err).Kind != apperr.KindConflict {
+			t.Errorf("Create with a used hash error = %v, want a conflict", err)
+		}
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		repo := newRepo(t)
+		old := createToken(t, repo, "user-1", "hash-1")
+
+		next := newToken("user-1", "hash-2")
+		if err := repo.Rotate(ctx, old, next); err != nil {
+			t.Fatal(err)
+		}
+		revoked, err := repo.GetByHash(ctx, "hash-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked.RevokedAt == nil || revoked.ReplacedBy == nil || *revoked.ReplacedBy != next.ID {
+			t.Errorf("old token after Rotate = %+v, want it revoked and replaced by %s", revoked, next.ID)
+		}
+		if got, err := repo.GetByHash(ctx, "hash-2"); err != nil || got.RevokedAt != nil {
+			t.Errorf("new token = %+v, %v; want it stored and valid", got, err)
+		}
+
+		// Reusar el token viejo no emite otro y no guarda nada.
+		if err := repo.Rotate(ctx, old, newToken("user-1", "hash-3")); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("second Rotate error = %v, want ErrInvalidToken", err)
+		}
+		if _, err := repo.GetByHash(ctx, "hash-3"); !errors.Is(err, auth.ErrInvalidToken) {
+			t.Errorf("token from a rejected Rotate was stored (error %v)", err)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		repo := newRepo(t)
+		first := createToken(t, repo, "user-1", "hash-1")
+		createToken(t, repo, "user-1", "hash-2")
+		createToken(t, repo, "user-2", "hash-3")
+
+		if err := repo.Revoke(ctx, first.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := repo.GetByHash(ctx, "hash-1"); got.RevokedAt == nil {
+			t.Error("Revoke did not revoke the token")
+		}
+		if got, _ := repo.GetByHash(ctx, "hash-2"); got.RevokedAt != nil {
+			t.Error("Revoke revoked another token")
+		}
+		if err := repo.Revoke(ctx, "missing"); err != nil {
+			t.Errorf("Revoke(missing) error = %v, want nil", err)
+		}
+
+		if err := repo.RevokeAll(ctx, "user-1"); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := repo.GetByHash(ctx, "hash-2"); got.RevokedAt == nil {
+			t.Error("RevokeAll did not revoke the user's tokens")
+		}
+		if got, _ := repo.GetByHash(ctx, "hash-3"); got.RevokedAt != nil {
+			t.Error("RevokeAll revoked another user's token")
+		}
+	})
+}
+
+func newToken(userID, hash string) *domain.RefreshToken {
+	return &domain.RefreshToken{UserID: userID, TokenHash: hash, ExpiresAt: time.Now().Add(time.Hour)}
+}
+
+func createToken(t *testing.T, repo auth.Repository, userID, hash string) *domain.RefreshToken {
+	t.Helper()
+	token := newToken(userID, hash)
+	if err := repo.Create(context.Background(), token); err != nil {
+		t.Fatalf("create token %s: %v", hash, err)
+	}
+	return token
+}
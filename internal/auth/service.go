@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/tracing"
+	"log/slog"
+	"time"
+)
+
+type (
+	Tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+
+	Service interface {
+		Login(ctx context.Context, email, password string) (*Tokens, error)
+		Refresh(ctx context.Context, refreshToken string) (*Tokens, error)
+		Logout(ctx context.Context, refreshToken string) error
+		Authenticate(ctx context.Context, accessToken string) (*domain.User, error)
+	}
+
+	service struct {
+		log        *slog.Logger
+		repo       Repository
+		userSrv    user.Service
+		signer     *Signer
+		accessTTL  time.Duration
+		refreshTTL time.Duration
+	}
+)
+
+var tracer = tracing.Tracer("internal/auth")
+
+func NewService(repo Repository, logger *slog.Logger, userSrv user.Service, signer *Signer, accessTTL, refreshTTL time.Duration) Service {
+	return &service{
+		log:        logger,
+		repo:       repo,
+		userSrv:    userSrv,
+		signer:     signer,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+	}
+}
+
+func (s service) Login(ctx context.Context, email, password string) (*Tokens, error) {
+	ctx, span := tracer.Start(ctx, "auth.Login")
+	defer span.End()
+
+	u, err := s.userSrv.Login(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, record, err := s.newRefreshToken(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+	return s.tokens(u.ID, refresh)
+}
+
+// Refresh entrega un nuevo par de tokens y revoca el refresh token usado.
+// Si se presenta un token ya revocado asumimos que fue robado y revocamos
+// todas las sesiones del usuario.
+func (s service) Refresh(ctx context.Context, refreshToken string) (*Tokens, error) {
+	ctx, span := tracer.Start(ctx, "auth.Refresh")
+	defer span.End()
+
+	current, err := s.repo.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if current.RevokedAt != nil {
+		s.log.WarnContext(ctx, "revoked refresh token reused, revoking user sessions", "user_id", current.UserID)
+		if err := s.repo.RevokeAll(ctx, current.UserID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	refresh, next, err := s.newRefreshToken(current.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Rotate(ctx, current, next); err != nil {
+		return nil, err
+	}
+	return s.tokens(current.UserID, refresh)
+}
+
+func (s service) Logout(ctx context.Context, refreshToken string) error {
+	ctx, span := tracer.Start(ctx, "auth.Logout")
+	defer span.End()
+
+	current, err := s.repo.GetByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+	return s.repo.Revoke(ctx, current.ID)
+}
+
+// Authenticate valida el access token y carga al usuario, de modo que un
+// usuario eliminado o con el rol cambiado se refleje en la siguiente petición.
+func (s service) Authenticate(ctx context.Context, accessToken string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "auth.Authenticate")
+	defer span.End()
+
+	userID, err := s.signer.Verify(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.userSrv.Get(ctx, userID)
+	if errors.Is(err, user.ErrNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s service) tokens(userID, refresh string) (*Tokens, error) {
+	access, err := s.signer.Sign(userID, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTTL.Seconds()),
+	}, nil
+}
+
+func (s service) newRefreshToken(userID string) (string, *domain.RefreshToken, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	return token, &domain.RefreshToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
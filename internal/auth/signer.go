@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"time"
+)
+
+// Signer firma y valida los access tokens con HS256 o RS256.
+type Signer struct {
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+	issuer    string
+}
+
+var (
+	ErrInvalidToken  = apperr.Unauthorized("invalid_token", "invalid or expired token")
+	ErrTokenRequired = apperr.Unauthorized("token_required", "authorization token is required")
+)
+
+func NewHS256Signer(secret []byte, issuer string) (*Signer, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("jwt secret must be at least 32 bytes")
+	}
+	return &Signer{
+		method:    jwt.SigningMethodHS256,
+		signKey:   secret,
+		verifyKey: secret,
+		issuer:    issuer,
+	}, nil
+}
+
+func NewRS256Signer(privatePEM, publicPEM []byte, issuer string) (*Signer, error) {
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		return nil, err
+	}
+	var publicKey *rsa.PublicKey
+	if len(publicPEM) > 0 {
+		publicKey, err = jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		publicKey = &privateKey.PublicKey
+	}
+	return &Signer{
+		method:    jwt.SigningMethodRS256,
+		signKey:   privateKey,
+		verifyKey: publicKey,
+		issuer:    issuer,
+	}, nil
+}
+
+func (s *Signer) Sign(userID string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   userID,
+		Issuer:    s.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	return jwt.NewWithClaims(s.method, claims).SignedString(s.signKey)
+}
+
+// Verify devuelve el id del usuario del token. Solo acepta el algoritmo
+// configurado para evitar que un token HS256 se valide con la llave pública.
+func (s *Signer) Verify(token string) (string, error) {
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.verifyKey, nil
+	},
+		jwt.WithValidMethods([]string{s.method.Alg()}),
+		jwt.WithIssuer(s.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.Subject == "" {
+		return "", ErrInvalidToken
+	}
+	return claims.Subject, nil
+}
@@ -2,9 +2,13 @@ package course
 
 import (
 	"encoding/json"
-	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/policy"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
 	"github.com/raminpz/gocourse_web/pkg/meta"
+	"github.com/raminpz/gocourse_web/pkg/response"
+	"github.com/raminpz/gocourse_web/pkg/validate"
 	"net/http"
 	"strconv"
 )
@@ -13,80 +17,111 @@ type (
 	Controller func(w http.ResponseWriter, r *http.Request)
 
 	Endpoint struct {
-		Create Controller
-		GetAll Controller
-		Get    Controller
-		Update Controller
-		Delete Controller
+		Create             Controller
+		GetAll             Controller
+		Get                Controller
+		Update             Controller
+		Delete             Controller
+		AddPrerequisite    Controller
+		RemovePrerequisite Controller
+		GetPrerequisites   Controller
+		AssignInstructor   Controller
+		UnassignInstructor Controller
+		GetTaught          Controller
 	}
 
 	CreateRequest struct {
-		Name      string `json:"name"`
-		StartDate string `json:"start_date"`
-		EndDate   string `json:"end_date"`
+		Name            string  `json:"name" validate:"required,max=50"`
+		StartDate       string  `json:"start_date" validate:"required,date"`
+		EndDate         string  `json:"end_date" validate:"required,date"`
+		Capacity        int     `json:"capacity" validate:"min=0"`
+		EnrollmentOpen  *string `json:"enrollment_open" validate:"date"`
+		EnrollmentClose *string `json:"enrollment_close" validate:"date"`
+	}
+
+	PrerequisiteRequest struct {
+		PrerequisiteID string `json:"prerequisite_id"`
+	}
+
+	InstructorRequest struct {
+		UserID string `json:"user_id"`
 	}
 
 	GetAllRequest struct {
 		Name string `json:"name"`
 	}
 
+	// UpdateRequest es una actualización parcial: los campos ausentes no se
+	// validan ni se modifican.
 	UpdateRequest struct {
-		Name      string `json:"name"`
-		StartDate string `json:"start_date"`
-		EndDate   string `json:"end_date"`
+		Name            *string `json:"name" validate:"required,max=50"`
+		StartDate       *string `json:"start_date" validate:"required,date"`
+		EndDate         *string `json:"end_date" validate:"required,date"`
+		Capacity        *int    `json:"capacity" validate:"min=0"`
+		EnrollmentOpen  *string `json:"enrollment_open" validate:"date"`
+		EnrollmentClose *string `json:"enrollment_close" validate:"date"`
 	}
 
-	Response struct {
-		Status int         `json:"status"`
-		Data   interface{} `json:"data"`
-		Err    string      `json:"err,omitempty"`
-		Meta   *meta.Meta  `json:"meta,omitempty"`
+	// legacyResponse es el formato V1 de los cursos: el error va en "err" y
+	// "data" se envía siempre, aunque sea null.
+	legacyResponse struct {
+		Status  int         `json:"status"`
+		Data    interface{} `json:"data"`
+		Err     string      `json:"err,omitempty"`
+		Code    string      `json:"code,omitempty"`
+		Details interface{} `json:"details,omitempty"`
+		Meta    *meta.Meta  `json:"meta,omitempty"`
 	}
 )
 
 func MakeEndpoints(s Service) Endpoint {
 	return Endpoint{
-		Create: makeCreateEndpoint(s),
-		GetAll: makeGetAllEndpoint(s),
-		Get:    makeGetEndpoint(s),
-		Update: makeUpdateEndpoint(s),
-		Delete: makeDeleteEndpoint(s),
+		Create:             makeCreateEndpoint(s),
+		GetAll:             makeGetAllEndpoint(s),
+		Get:                makeGetEndpoint(s),
+		Update:             makeUpdateEndpoint(s),
+		Delete:             makeDeleteEndpoint(s),
+		AddPrerequisite:    makeAddPrerequisiteEndpoint(s),
+		RemovePrerequisite: makeRemovePrerequisiteEndpoint(s),
+		GetPrerequisites:   makeGetPrerequisitesEndpoint(s),
+		AssignInstructor:   makeAssignInstructorEndpoint(s),
+		UnassignInstructor: makeUnassignInstructorEndpoint(s),
+		GetTaught:          makeGetTaughtEndpoint(s),
 	}
 }
 
 func makeCreateEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 
+		if !authorize(w, r, policy.CreateCourse, policy.Resource{}) {
+			return
+		}
+
 		var req CreateRequest
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: fmt.Sprintf("%v", err)})
+			respondError(w, r, apperr.Validation("invalid_request", "invalid request format"))
 			return
 		}
-		if req.Name == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "name is required"})
+		if err := validate.Struct(req); err != nil {
+			respondError(w, r, err)
 			return
 		}
-		if req.StartDate == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "start_date is required"})
-			return
-		}
-		if req.EndDate == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "end_date is required"})
-			return
-		}
-		course, err := s.Create(req.Name, req.StartDate, req.EndDate)
+		course, err := s.Create(r.Context(), req.Name, req.StartDate, req.EndDate, req.Capacity, req.EnrollmentOpen, req.EnrollmentClose)
 		if err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: err.Error()})
+			respondError(w, r, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: course})
+		// El instructor que crea el curso queda como su instructor.
+		if actor := policy.ActorFrom(r.Context()); actor.Role == domain.RoleInstructor {
+			if err := s.AssignInstructor(r.Context(), course.ID, actor.ID); err != nil {
+				respondError(w, r, err)
+				return
+			}
+		}
+
+		respond(w, r, response.OK(course))
 
 	}
 }
@@ -95,103 +130,235 @@ func makeGetEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := mux.Vars(r)
 		id := path["id"]
-		course, err := s.Get(id)
+		if !authorize(w, r, policy.ReadCourse, policy.Resource{}) {
+			return
+		}
+		course, err := s.Get(r.Context(), id)
 		if err != nil {
-			w.WriteHeader(404)
-			json.NewEncoder(w).Encode(&Response{Status: 404, Err: "course does not exist"})
+			respondError(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: course})
+		respond(w, r, response.OK(course))
 	}
 
 }
 
 func makeGetAllEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
+		if !authorize(w, r, policy.ReadCourse, policy.Resource{}) {
+			return
+		}
 
 		v := r.URL.Query()
 		filters := Filters{
-			Name: v.Get("name"),
+			Name:         v.Get("name"),
+			InstructorID: v.Get("instructor_id"),
 		}
+		listCourses(s, w, r, filters)
+	}
+}
 
-		limit, err := strconv.Atoi(v.Get("limit"))
-		if err != nil || limit <= 0 {
-			limit = 10 // valor por defecto
-		}
-		page, err := strconv.Atoi(v.Get("page"))
-		if err != nil || page <= 0 {
-			page = 1 // valor por defecto
+// makeGetTaughtEndpoint lista los cursos que dicta el usuario {id}.
+func makeGetTaughtEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(w, r, policy.ReadCourse, policy.Resource{}) {
+			return
 		}
 
-		count, err := s.Count(filters)
-		if err != nil {
-			w.WriteHeader(500)
-			json.NewEncoder(w).Encode(&Response{Status: 500, Err: err.Error()})
+		path := mux.Vars(r)
+		listCourses(s, w, r, Filters{InstructorID: path["id"]})
+	}
+}
+
+func listCourses(s Service, w http.ResponseWriter, r *http.Request, filters Filters) {
+	v := r.URL.Query()
+
+	limit, err := strconv.Atoi(v.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10 // valor por defecto
+	}
+	page, err := strconv.Atoi(v.Get("page"))
+	if err != nil || page <= 0 {
+		page = 1 // valor por defecto
+	}
+
+	count, err := s.Count(r.Context(), filters)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	meta, err := meta.New(page, limit, count)
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+
+	courses, err := s.GetAll(r.Context(), filters, meta.Limit(), meta.Offset())
+	if err != nil {
+		respondError(w, r, err)
+		return
+	}
+	respond(w, r, response.Page(courses, meta))
+}
+
+func makeUpdateEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req UpdateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, r, apperr.Validation("invalid_request", "invalid request format"))
 			return
 		}
-
-		meta, err := meta.New(page, limit, count)
-		if err != nil {
-			w.WriteHeader(500)
-			json.NewEncoder(w).Encode(&Response{Status: 500, Err: err.Error()})
+		if err := validate.Struct(req); err != nil {
+			respondError(w, r, err)
 			return
 		}
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.UpdateCourse, courseResource(s, r, id)) {
+			return
+		}
+		if err := s.Update(r.Context(), id, req.Name, req.StartDate, req.EndDate, req.Capacity, req.EnrollmentOpen, req.EnrollmentClose); err != nil {
+			respondError(w, r, err)
+			return
+		}
+		respond(w, r, response.OK("course updated successfully"))
 
-		courses, err := s.GetAll(filters, meta.Limit(), meta.Offset())
-		if err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: err.Error()})
+	}
+}
+
+func makeDeleteEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.DeleteCourse, policy.Resource{}) {
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: courses, Meta: meta})
+		if err := s.Delete(r.Context(), id); err != nil {
+			respondError(w, r, err)
+			return
+		}
+		respond(w, r, response.OK("course deleted successfully"))
 	}
 }
 
-func makeUpdateEndpoint(s Service) Controller {
+func makeAddPrerequisiteEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var req UpdateRequest
+		var req PrerequisiteRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: fmt.Sprintf("invalid request format: %v", err)})
+			respondError(w, r, apperr.Validation("invalid_request", "invalid request format"))
 			return
 		}
-		if req.Name == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "name is required"})
+		if req.PrerequisiteID == "" {
+			respondError(w, r, apperr.Validation("prerequisite_id_required", "prerequisite_id is required"))
 			return
 		}
-		if req.StartDate == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "start_date is required"})
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.UpdateCourse, courseResource(s, r, id)) {
 			return
 		}
-		if req.EndDate == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "end_date is required"})
+		if err := s.AddPrerequisite(r.Context(), id, req.PrerequisiteID); err != nil {
+			respondError(w, r, err)
 			return
 		}
+		respond(w, r, response.OK("prerequisite added successfully"))
+	}
+}
+
+func makeRemovePrerequisiteEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
 		path := mux.Vars(r)
 		id := path["id"]
-		if err := s.Update(id, &req.Name, &req.StartDate, &req.EndDate); err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "course does not exist"})
+		if !authorize(w, r, policy.UpdateCourse, courseResource(s, r, id)) {
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: "course updated successfully"})
+		if err := s.RemovePrerequisite(r.Context(), id, path["prerequisite_id"]); err != nil {
+			respondError(w, r, err)
+			return
+		}
+		respond(w, r, response.OK("prerequisite removed successfully"))
+	}
+}
 
+func makeGetPrerequisitesEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.ReadCourse, policy.Resource{}) {
+			return
+		}
+		courses, err := s.Prerequisites(r.Context(), id)
+		if err != nil {
+			respondError(w, r, err)
+			return
+		}
+		respond(w, r, response.OK(courses))
 	}
 }
 
-func makeDeleteEndpoint(s Service) Controller {
+func makeAssignInstructorEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req InstructorRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			respondError(w, r, apperr.Validation("invalid_request", "invalid request format"))
+			return
+		}
+		if req.UserID == "" {
+			respondError(w, r, apperr.Validation("user_id_required", "user_id is required"))
+			return
+		}
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.UpdateCourse, courseResource(s, r, id)) {
+			return
+		}
+		if err := s.AssignInstructor(r.Context(), id, req.UserID); err != nil {
+			respondError(w, r, err)
+			return
+		}
+		respond(w, r, response.OK("instructor assigned successfully"))
+	}
+}
+
+func makeUnassignInstructorEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := mux.Vars(r)
 		id := path["id"]
-		if err := s.Delete(id); err != nil {
-			w.WriteHeader(404)
-			json.NewEncoder(w).Encode(&Response{Status: 404, Err: "course does not exist"})
+		if !authorize(w, r, policy.UpdateCourse, courseResource(s, r, id)) {
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: "course deleted successfully"})
+		if err := s.UnassignInstructor(r.Context(), id, path["user_id"]); err != nil {
+			respondError(w, r, err)
+			return
+		}
+		respond(w, r, response.OK("instructor unassigned successfully"))
 	}
 }
+
+func authorize(w http.ResponseWriter, r *http.Request, action policy.Action, res policy.Resource) bool {
+	if err := policy.Authorize(policy.ActorFrom(r.Context()), action, res); err != nil {
+		respondError(w, r, err)
+		return false
+	}
+	return true
+}
+
+func respond(w http.ResponseWriter, r *http.Request, res *response.Response) {
+	response.Write(w, r, res, legacy)
+}
+
+func legacy(res *response.Response) interface{} {
+	return legacyResponse(*res)
+}
+
+func respondError(w http.ResponseWriter, r *http.Request, err error) {
+	respond(w, r, response.FromError(err))
+}
+
+// courseResource indica si quien hace la petición dicta el curso id.
+func courseResource(s Service, r *http.Request, id string) policy.Resource {
+	actor := policy.ActorFrom(r.Context())
+	instructs, err := s.IsInstructor(r.Context(), id, actor.ID)
+	return policy.Resource{Instructs: err == nil && instructs}
+}
@@ -0,0 +1,277 @@
+package course
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryRepo guarda los cursos en memoria con la misma semántica que repo.
+// Las relaciones se guardan como las tablas intermedias, por ID, y los
+// instructores se leen de users al devolver un curso.
+type memoryRepo struct {
+	log           *slog.Logger
+	users         user.Repository
+	mu            sync.RWMutex
+	courses       map[string]domain.Course
+	prerequisites map[string][]string
+	instructors   map[string][]string
+}
+
+func NewMemoryRepo(logger *slog.Logger, users user.Repository) Repository {
+	return &memoryRepo{
+		log:           logger,
+		users:         users,
+		courses:       make(map[string]domain.Course),
+		prerequisites: make(map[string][]string),
+		instructors:   make(map[string][]string),
+	}
+}
+
+func (r *memoryRepo) Create(ctx context.Context, course *domain.Course) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if course.ID == "" {
+		course.ID = uuid.New().String()
+	}
+	if _, ok := r.courses[course.ID]; ok {
+		err := apperr.DB(gorm.ErrDuplicatedKey, ErrNotFound)
+		r.log.ErrorContext(ctx, "error creating course", "error", err)
+		return err
+	}
+	now := time.Now()
+	if course.CreatedAt.IsZero() {
+		course.CreatedAt = now
+	}
+	if course.UpdatedAt.IsZero() {
+		course.UpdatedAt = now
+	}
+
+	stored := *course
+	stored.Prerequisites = nil
+	stored.Instructors = nil
+	r.courses[course.ID] = stored
+	r.log.InfoContext(ctx, "course created", "course_id", course.ID)
+	return nil
+}
+
+func (r *memoryRepo) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Course, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	courses := r.filter(filters)
+	sort.Slice(courses, func(i, j int) bool {
+		// A igual fecha se ordena por ID para que las páginas sean estables.
+		if !courses[i].CreatedAt.Equal(courses[j].CreatedAt) {
+			return courses[i].CreatedAt.After(courses[j].CreatedAt)
+		}
+		return courses[i].ID < courses[j].ID
+	})
+	// Igual que Limit y Offset de gorm: un límite negativo no limita y 0 no
+	// devuelve nada.
+	if offset > 0 {
+		if offset >= len(courses) {
+			return []domain.Course{}, nil
+		}
+		courses = courses[offset:]
+	}
+	if limit >= 0 && limit < len(courses) {
+		courses = courses[:limit]
+	}
+	return courses, nil
+}
+
+// Get devuelve el curso con sus prerrequisitos e instructores; como el
+// Preload, omite los que estén borrados.
+func (r *memoryRepo) Get(ctx context.Context, id string) (*domain.Course, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	course, ok := r.courses[id]
+	if !ok || course.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	course.Prerequisites = []domain.Course{}
+	for _, prerequisiteID := range r.prerequisites[id] {
+		if prerequisite, ok := r.courses[prerequisiteID]; ok && !prerequisite.DeletedAt.Valid {
+			course.Prerequisites = append(course.Prerequisites, prerequisite)
+		}
+	}
+	course.Instructors = []domain.User{}
+	for _, userID := range r.instructors[id] {
+		instructor, err := r.users.GetByID(ctx, userID)
+		if errors.Is(err, user.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		course.Instructors = append(course.Instructors, *instructor)
+	}
+	return &course, nil
+}
+
+func (r *memoryRepo) GetUnscoped(ctx context.Context, id string) (*domain.Course, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	course, ok := r.courses[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &course, nil
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	course, ok := r.courses[id]
+	if !ok || course.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	course.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.courses[id] = course
+	return nil
+}
+
+func (r *memoryRepo) Update(ctx context.Context, id string, name *string, startDate *time.Time, endDate *time.Time, capacity *int, enrollmentOpen *time.Time, enrollmentClose *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	course, ok := r.courses[id]
+	if !ok || course.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if name != nil {
+		course.Name = *name
+	}
+	if startDate != nil {
+		course.StartDate = *startDate
+	}
+	if endDate != nil {
+		course.EndDate = *endDate
+	}
+	if capacity != nil {
+		course.Capacity = *capacity
+	}
+	if enrollmentOpen != nil {
+		open := *enrollmentOpen
+		course.EnrollmentOpen = &open
+	}
+	if enrollmentClose != nil {
+		closeAt := *enrollmentClose
+		course.EnrollmentClose = &closeAt
+	}
+	course.UpdatedAt = time.Now()
+	r.courses[id] = course
+	return nil
+}
+
+func (r *memoryRepo) Count(ctx context.Context, filters Filters) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.filter(filters)), nil
+}
+
+func (r *memoryRepo) AddPrerequisite(ctx context.Context, id, prerequisiteID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prerequisites[id] = appendUnique(r.prerequisites[id], prerequisiteID)
+	return nil
+}
+
+func (r *memoryRepo) RemovePrerequisite(ctx context.Context, id, prerequisiteID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prerequisites[id] = remove(r.prerequisites[id], prerequisiteID)
+	return nil
+}
+
+func (r *memoryRepo) AddInstructor(ctx context.Context, id, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.instructors[id] = appendUnique(r.instructors[id], userID)
+	return nil
+}
+
+func (r *memoryRepo) RemoveInstructor(ctx context.Context, id, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.instructors[id] = remove(r.instructors[id], userID)
+	return nil
+}
+
+func (r *memoryRepo) IsInstructor(ctx context.Context, id, userID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, instructor := range r.instructors[id] {
+		if instructor == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// filter devuelve los cursos no borrados que cumplen filters, igual que
+// applyFilters.
+func (r *memoryRepo) filter(filters Filters) []domain.Course {
+	courses := []domain.Course{}
+	for _, course := range r.courses {
+		if course.DeletedAt.Valid {
+			continue
+		}
+		if !strings.Contains(strings.ToLower(course.Name), strings.ToLower(filters.Name)) {
+			continue
+		}
+		if filters.InstructorID != "" {
+			teaches := false
+			for _, userID := range r.instructors[course.ID] {
+				teaches = teaches || userID == filters.InstructorID
+			}
+			if !teaches {
+				continue
+			}
+		}
+		courses = append(courses, course)
+	}
+	return courses
+}
+
+// appendUnique agrega id a ids si no estaba, como el insert en la tabla
+// intermedia que ignora las filas repetidas.
+func appendUnique(ids []string, id string) []string {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+func remove(ids []string, id string) []string {
+	kept := make([]string, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			kept = append(kept, existing)
+		}
+	}
+	return kept
+}
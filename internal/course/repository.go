@@ -1,75 +1,96 @@
 package course
 
 import (
+	"context"
 	"fmt"
 	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
 	"gorm.io/gorm"
-	"log"
+	"log/slog"
 	"time"
 )
 
 type (
 	Repository interface {
-		Create(course *domain.Course) error
-		GetAll(filters Filters, limit, offset int) ([]domain.Course, error)
-		Get(id string) (*domain.Course, error)
-		Update(id string, name *string, startDate *time.Time, endDate *time.Time) error
-		Delete(id string) error
-		Count(filters Filters) (int, error)
+		Create(ctx context.Context, course *domain.Course) error
+		GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Course, error)
+		Get(ctx context.Context, id string) (*domain.Course, error)
+		GetUnscoped(ctx context.Context, id string) (*domain.Course, error)
+		Update(ctx context.Context, id string, name *string, startDate *time.Time, endDate *time.Time, capacity *int, enrollmentOpen *time.Time, enrollmentClose *time.Time) error
+		Delete(ctx context.Context, id string) error
+		Count(ctx context.Context, filters Filters) (int, error)
+		AddPrerequisite(ctx context.Context, id, prerequisiteID string) error
+		RemovePrerequisite(ctx context.Context, id, prerequisiteID string) error
+		AddInstructor(ctx context.Context, id, userID string) error
+		RemoveInstructor(ctx context.Context, id, userID string) error
+		IsInstructor(ctx context.Context, id, userID string) (bool, error)
 	}
 	repo struct {
 		db  *gorm.DB
-		log *log.Logger
+		log *slog.Logger
 	}
 )
 
-func NewRepo(db *gorm.DB, logger *log.Logger) Repository {
+func NewRepo(db *gorm.DB, logger *slog.Logger) Repository {
 	return &repo{
 		db:  db,
 		log: logger,
 	}
 }
 
-func (r *repo) Create(course *domain.Course) error {
-	if err := r.db.Create(course).Error; err != nil {
-		r.log.Println("Error creating course:", err)
-		return err
+func (r *repo) Create(ctx context.Context, course *domain.Course) error {
+	if err := r.db.WithContext(ctx).Create(course).Error; err != nil {
+		r.log.ErrorContext(ctx, "error creating course", "error", err)
+		return apperr.DB(err, ErrNotFound)
 	}
-	r.log.Println("Course created with id:", course.ID)
+	r.log.InfoContext(ctx, "course created", "course_id", course.ID)
 	return nil
 }
 
-func (r *repo) GetAll(filters Filters, limit, offset int) ([]domain.Course, error) {
+func (r *repo) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Course, error) {
 	var courses []domain.Course
-	tx := r.db.Model(&courses)
+	tx := r.db.WithContext(ctx).Model(&courses)
 	tx = applyFilters(tx, filters)
 	tx = tx.Limit(limit).Offset(offset)
 	result := tx.Order("created_at desc").Find(&courses)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, apperr.DB(result.Error, ErrNotFound)
 	}
 	return courses, nil
 }
 
-func (r *repo) Get(id string) (*domain.Course, error) {
+func (r *repo) Get(ctx context.Context, id string) (*domain.Course, error) {
 	course := domain.Course{ID: id}
-	result := r.db.First(&course)
+	result := r.db.WithContext(ctx).Preload("Prerequisites").Preload("Instructors").First(&course)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, apperr.DB(result.Error, ErrNotFound)
 	}
 	return &course, nil
 }
 
-func (r *repo) Delete(id string) error {
+// GetUnscoped devuelve el curso aunque esté borrado, sin sus relaciones.
+func (r *repo) GetUnscoped(ctx context.Context, id string) (*domain.Course, error) {
 	course := domain.Course{ID: id}
-	result := r.db.Delete(&course)
+	result := r.db.WithContext(ctx).Unscoped().First(&course)
 	if result.Error != nil {
-		return result.Error
+		return nil, apperr.DB(result.Error, ErrNotFound)
+	}
+	return &course, nil
+}
+
+func (r *repo) Delete(ctx context.Context, id string) error {
+	course := domain.Course{ID: id}
+	result := r.db.WithContext(ctx).Delete(&course)
+	if result.Error != nil {
+		return apperr.DB(result.Error, ErrNotFound)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *repo) Update(id string, name *string, startDate *time.Time, endDate *time.Time) error {
+func (r *repo) Update(ctx context.Context, id string, name *string, startDate *time.Time, endDate *time.Time, capacity *int, enrollmentOpen *time.Time, enrollmentClose *time.Time) error {
 	values := make(map[string]interface{})
 	if name != nil {
 		values["name"] = *name
@@ -80,27 +101,83 @@ func (r *repo) Update(id string, name *string, startDate *time.Time, endDate *ti
 	if endDate != nil {
 		values["end_date"] = *endDate
 	}
-	result := r.db.Model(&domain.Course{}).Where("id = ?", id).Updates(values)
+	if capacity != nil {
+		values["capacity"] = *capacity
+	}
+	if enrollmentOpen != nil {
+		values["enrollment_open"] = *enrollmentOpen
+	}
+	if enrollmentClose != nil {
+		values["enrollment_close"] = *enrollmentClose
+	}
+	result := r.db.WithContext(ctx).Model(&domain.Course{}).Where("id = ?", id).Updates(values)
 	if result.Error != nil {
-		return result.Error
+		return apperr.DB(result.Error, ErrNotFound)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *repo) Count(filters Filters) (int, error) {
+func (r *repo) Count(ctx context.Context, filters Filters) (int, error) {
 	var count int64
-	tx := r.db.Model(&domain.Course{})
+	tx := r.db.WithContext(ctx).Model(&domain.Course{})
 	tx = applyFilters(tx, filters)
 	if err := tx.Count(&count).Error; err != nil {
-		return 0, err
+		return 0, apperr.DB(err, ErrNotFound)
 	}
 	return int(count), nil
 
 }
 
+func (r *repo) AddPrerequisite(ctx context.Context, id, prerequisiteID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.Course{ID: id}).
+		Omit("Prerequisites.*").
+		Association("Prerequisites").
+		Append(&domain.Course{ID: prerequisiteID})
+	return apperr.DB(err, ErrNotFound)
+}
+
+func (r *repo) RemovePrerequisite(ctx context.Context, id, prerequisiteID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.Course{ID: id}).
+		Association("Prerequisites").
+		Delete(&domain.Course{ID: prerequisiteID})
+	return apperr.DB(err, ErrNotFound)
+}
+
+func (r *repo) AddInstructor(ctx context.Context, id, userID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.Course{ID: id}).
+		Omit("Instructors.*").
+		Association("Instructors").
+		Append(&domain.User{ID: userID})
+	return apperr.DB(err, ErrNotFound)
+}
+
+func (r *repo) RemoveInstructor(ctx context.Context, id, userID string) error {
+	err := r.db.WithContext(ctx).Model(&domain.Course{ID: id}).
+		Association("Instructors").
+		Delete(&domain.User{ID: userID})
+	return apperr.DB(err, ErrNotFound)
+}
+
+func (r *repo) IsInstructor(ctx context.Context, id, userID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("course_instructors").
+		Where("course_id = ? AND user_id = ?", id, userID).
+		Count(&count).Error
+	if err != nil {
+		return false, apperr.DB(err, ErrNotFound)
+	}
+	return count > 0, nil
+}
+
 func applyFilters(txt *gorm.DB, filters Filters) *gorm.DB {
 	if filters.Name != "" {
-		txt = txt.Where("LOWER(name) LIKE (?)", fmt.Sprintf("%%%s%%", filters.Name))
+		txt = txt.Where("LOWER(name) LIKE LOWER(?)", fmt.Sprintf("%%%s%%", filters.Name))
+	}
+	if filters.InstructorID != "" {
+		txt = txt.Where("id IN (SELECT course_id FROM course_instructors WHERE user_id = ?)", filters.InstructorID)
 	}
 	return txt
 
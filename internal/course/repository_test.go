@@ -0,0 +1,253 @@
+package course_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/testdb"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRepository(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	t.Run("memory", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) (course.Repository, user.Repository) {
+			users := user.NewMemoryRepo(logger)
+			return course.NewMemoryRepo(logger, users), users
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) (course.Repository, user.Repository) {
+			db := testdb.Open(t)
+			return course.NewRepo(db, logger), user.NewRepo(logger, db)
+		})
+	})
+}
+
+// RepositorySuite comprueba el contrato de course.Repository; newRepo devuelve
+// repositorios vacíos de cursos y de usuarios que comparten almacenamiento.
+func RepositorySuite(t *testing.T, newRepo func(t *testing.T) (course.Repository, user.Repository)) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		open := date(2030, 1, 1)
+		c := &domain.Course{Name: "Go", StartDate: date(2030, 2, 1), EndDate: date(2030, 6, 1), Capacity: 3, EnrollmentOpen: &open}
+		if err := repo.Create(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+		if c.ID == "" {
+			t.Fatal("Create did not assign an ID")
+		}
+
+		got, err := repo.Get(ctx, c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "Go" || got.Capacity != 3 || !got.StartDate.Equal(c.StartDate) || got.EnrollmentOpen == nil || !got.EnrollmentOpen.Equal(open) || got.EnrollmentClose != nil {
+			t.Errorf("Get = %+v", got)
+		}
+		if len(got.Prerequisites) != 0 || len(got.Instructors) != 0 {
+			t.Errorf("new course has prerequisites %v and instructors %v", got.Prerequisites, got.Instructors)
+		}
+		if _, err := repo.Get(ctx, "missing"); !errors.Is(err, course.ErrNotFound) {
+			t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetUnscoped(ctx, "missing"); !errors.Is(err, course.ErrNotFound) {
+			t.Errorf("GetUnscoped(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("list newest first with filters and pagination", func(t *testing.T) {
+		repo, users := newRepo(t)
+		teacher := createUser(t, users, "t@example.com", "1")
+		base := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		names := []string{"Go básico", "Rust", "Go avanzado"}
+		ids := make([]string, len(names))
+		for i, name := range names {
+			ids[i] = createCourse(t, repo, name, 0, base.Add(time.Duration(i)*time.Hour)).ID
+		}
+		deleted := createCourse(t, repo, "Go borrado", 0, base.Add(time.Minute))
+		if err := repo.Delete(ctx, deleted.ID); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{ids[1], ids[2], deleted.ID} {
+			if err := repo.AddInstructor(ctx, id, teacher.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		tests := []struct {
+			name          string
+			filters       course.Filters
+			limit, offset int
+			want          []string
+		}{
+			{"first page", course.Filters{}, 2, 0, []string{ids[2], ids[1]}},
+			{"last page", course.Filters{}, 2, 2, []string{ids[0]}},
+			{"past the end", course.Filters{}, 2, 3, nil},
+			{"zero limit", course.Filters{}, 0, 0, nil},
+			{"negative limit", course.Filters{}, -1, 0, []string{ids[2], ids[1], ids[0]}},
+			{"name, any case", course.Filters{Name: "gO"}, 10, 0, []string{ids[2], ids[0]}},
+			{"instructor", course.Filters{InstructorID: teacher.ID}, 10, 0, []string{ids[2], ids[1]}},
+			{"name and instructor", course.Filters{Name: "go", InstructorID: teacher.ID}, 10, 0, []string{ids[2]}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				courses, err := repo.GetAll(ctx, tt.filters, tt.limit, tt.offset)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := courseIDs(courses); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("GetAll = %v, want %v", got, tt.want)
+				}
+			})
+		}
+
+		count, err := repo.Count(ctx, course.Filters{InstructorID: teacher.ID})
+		if err != nil || count != 2 {
+			t.Errorf("Count = %d, %v; want 2", count, err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		c := createCourse(t, repo, "Go", 1, time.Time{})
+
+		name, capacity := "Go 2", 0
+		closeAt := date(2030, 1, 15)
+		if err := repo.Update(ctx, c.ID, &name, nil, nil, &capacity, nil, &closeAt); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.Get(ctx, c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "Go 2" || got.Capacity != 0 || got.EnrollmentClose == nil || !got.EnrollmentClose.Equal(closeAt) || !got.StartDate.Equal(c.StartDate) {
+			t.Errorf("after Update got %+v", got)
+		}
+		if err := repo.Update(ctx, "missing", &name, nil, nil, nil, nil, nil); !errors.Is(err, course.ErrNotFound) {
+			t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("delete is soft", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		c := createCourse(t, repo, "Go", 0, time.Time{})
+		if err := repo.Delete(ctx, c.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Get(ctx, c.ID); !errors.Is(err, course.ErrNotFound) {
+			t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+		}
+		if got, err := repo.GetUnscoped(ctx, c.ID); err != nil || got.ID != c.ID {
+			t.Errorf("GetUnscoped after Delete = %v, %v; want the deleted course", got, err)
+		}
+		name := "Go 2"
+		if err := repo.Update(ctx, c.ID, &name, nil, nil, nil, nil, nil); !errors.Is(err, course.ErrNotFound) {
+			t.Errorf("Update after Delete error = %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, c.ID); !errors.Is(err, course.ErrNotFound) {
+			t.Errorf("second Delete error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("prerequisites", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		c := createCourse(t, repo, "Go avanzado", 0, time.Time{})
+		basic := createCourse(t, repo, "Go básico", 0, time.Time{})
+		gone := createCourse(t, repo, "Programación", 0, time.Time{})
+
+		for _, id := range []string{basic.ID, basic.ID, gone.ID} {
+			if err := repo.AddPrerequisite(ctx, c.ID, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := repo.Delete(ctx, gone.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.Get(ctx, c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids := courseIDs(got.Prerequisites); fmt.Sprint(ids) != fmt.Sprint([]string{basic.ID}) {
+			t.Errorf("prerequisites = %v, want only %s", ids, basic.ID)
+		}
+
+		if err := repo.RemovePrerequisite(ctx, c.ID, basic.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := repo.Get(ctx, c.ID); len(got.Prerequisites) != 0 {
+			t.Errorf("prerequisites after remove = %v, want none", courseIDs(got.Prerequisites))
+		}
+	})
+
+	t.Run("instructors", func(t *testing.T) {
+		repo, users := newRepo(t)
+		c := createCourse(t, repo, "Go", 0, time.Time{})
+		teacher := createUser(t, users, "t@example.com", "1")
+		gone := createUser(t, users, "g@example.com", "2")
+
+		for _, id := range []string{teacher.ID, teacher.ID, gone.ID} {
+			if err := repo.AddInstructor(ctx, c.ID, id); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := users.Delete(ctx, gone.ID); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.Get(ctx, c.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got.Instructors) != 1 || got.Instructors[0].ID != teacher.ID || got.Instructors[0].Email != "t@example.com" {
+			t.Errorf("instructors = %+v, want only %s", got.Instructors, teacher.ID)
+		}
+		if ok, err := repo.IsInstructor(ctx, c.ID, teacher.ID); err != nil || !ok {
+			t.Errorf("IsInstructor = %v, %v; want true", ok, err)
+		}
+
+		if err := repo.RemoveInstructor(ctx, c.ID, teacher.ID); err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := repo.IsInstructor(ctx, c.ID, teacher.ID); err != nil || ok {
+			t.Errorf("IsInstructor after remove = %v, %v; want false", ok, err)
+		}
+	})
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// createCourse crea un curso de 2030; con createdAt cero toma la hora actual.
+func createCourse(t *testing.T, repo course.Repository, name string, capacity int, createdAt time.Time) *domain.Course {
+	t.Helper()
+	c := &domain.Course{Name: name, StartDate: date(2030, 2, 1), EndDate: date(2030, 6, 1), Capacity: capacity, CreatedAt: createdAt}
+	if err := repo.Create(context.Background(), c); err != nil {
+		t.Fatalf("create course %s: %v", name, err)
+	}
+	return c
+}
+
+func createUser(t *testing.T, repo user.Repository, email, phone string) *domain.User {
+	t.Helper()
+	u := &domain.User{FirstName: "Ana", LastName: "López", Email: email, Phone: phone, Role: domain.RoleInstructor}
+	if err := repo.Create(context.Background(), u); err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return u
+}
+
+func courseIDs(courses []domain.Course) []string {
+	var ids []string
+	for _, c := range courses {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
@@ -1,109 +1,339 @@
 package course
 
 import (
+	"context"
 	"github.com/raminpz/gocourse_web/internal/domain"
-	"log"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/tracing"
+	"log/slog"
 	"time"
 )
 
 type (
 	Filters struct {
-		Name string
+		Name         string
+		InstructorID string
 	}
 	Service interface {
-		Create(name, startDate, endDate string) (*domain.Course, error)
-		GetAll(filters Filters, limit, offset int) ([]domain.Course, error)
-		Get(id string) (*domain.Course, error)
-		Update(id string, name, startDate, endDate *string) error
-		Delete(id string) error
-		Count(filters Filters) (int, error)
+		Create(ctx context.Context, name, startDate, endDate string, capacity int, enrollmentOpen, enrollmentClose *string) (*domain.Course, error)
+		GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Course, error)
+		Get(ctx context.Context, id string) (*domain.Course, error)
+		Update(ctx context.Context, id string, name, startDate, endDate *string, capacity *int, enrollmentOpen, enrollmentClose *string) error
+		Delete(ctx context.Context, id string) error
+		Count(ctx context.Context, filters Filters) (int, error)
+		AddPrerequisite(ctx context.Context, id, prerequisiteID string) error
+		RemovePrerequisite(ctx context.Context, id, prerequisiteID string) error
+		Prerequisites(ctx context.Context, id string) ([]domain.Course, error)
+		AssignInstructor(ctx context.Context, id, userID string) error
+		UnassignInstructor(ctx context.Context, id, userID string) error
+		IsInstructor(ctx context.Context, id, userID string) (bool, error)
 	}
+	// WaitlistPromoter promueve la lista de espera de un curso; Update lo llama
+	// cuando cambia la capacidad.
+	WaitlistPromoter func(ctx context.Context, courseID string) error
+
 	service struct {
-		log  *log.Logger
-		repo Repository
+		log     *slog.Logger
+		repo    Repository
+		userSrv user.Service
+		promote WaitlistPromoter
 	}
 )
 
-func NewService(repo Repository, logger *log.Logger) Service {
+var tracer = tracing.Tracer("internal/course")
+
+var (
+	ErrNotFound                = apperr.NotFound("course_not_found", "course does not exist")
+	ErrInvalidDate             = apperr.Validation("invalid_date", "dates must use the YYYY-MM-DD format")
+	ErrInvalidEnrollmentWindow = apperr.Validation("invalid_enrollment_window", "enrollment_open must be before enrollment_close")
+	ErrPrerequisiteCycle       = apperr.Conflict("prerequisite_cycle", "prerequisite would create a cycle")
+	ErrCannotTeach             = apperr.Validation("cannot_teach", "only instructors and admins can be assigned to a course")
+)
+
+func NewService(repo Repository, logger *slog.Logger, userSrv user.Service, promote WaitlistPromoter) Service {
 	return &service{
-		log:  logger,
-		repo: repo,
+		log:     logger,
+		repo:    repo,
+		userSrv: userSrv,
+		promote: promote,
 	}
 }
 
-func (s service) Create(name, startDate, endDate string) (*domain.Course, error) {
+func (s service) Create(ctx context.Context, name, startDate, endDate string, capacity int, enrollmentOpen, enrollmentClose *string) (*domain.Course, error) {
+	ctx, span := tracer.Start(ctx, "course.Create")
+	defer span.End()
 
 	startDateParsed, err := time.Parse("2006-01-02", startDate)
 	if err != nil {
-		s.log.Println("Error parsing start date:", err)
-		return nil, err
+		s.log.DebugContext(ctx, "invalid start date", "error", err)
+		return nil, invalidDate("start_date", err)
 	}
 
 	endDateParsed, err := time.Parse("2006-01-02", endDate)
 	if err != nil {
-		s.log.Println("Error parsing end date:", err)
-		return nil, err
+		s.log.DebugContext(ctx, "invalid end date", "error", err)
+		return nil, invalidDate("end_date", err)
+	}
+	enrollmentOpenParsed, err := parseOptionalDate(enrollmentOpen)
+	if err != nil {
+		s.log.DebugContext(ctx, "invalid enrollment open date", "error", err)
+		return nil, invalidDate("enrollment_open", err)
+	}
+	enrollmentCloseParsed, err := parseOptionalDate(enrollmentClose)
+	if err != nil {
+		s.log.DebugContext(ctx, "invalid enrollment close date", "error", err)
+		return nil, invalidDate("enrollment_close", err)
 	}
 	course := &domain.Course{
-		Name:      name,
-		StartDate: startDateParsed,
-		EndDate:   endDateParsed,
+		Name:            name,
+		StartDate:       startDateParsed,
+		EndDate:         endDateParsed,
+		Capacity:        capacity,
+		EnrollmentOpen:  enrollmentOpenParsed,
+		EnrollmentClose: enrollmentCloseParsed,
+	}
+	if course.EnrollmentOpen != nil && !course.EnrollmentOpen.Before(course.EnrollmentCloseAt()) {
+		return nil, ErrInvalidEnrollmentWindow
 	}
-	if err := s.repo.Create(course); err != nil {
+	if err := s.repo.Create(ctx, course); err != nil {
 		return nil, err
 	}
 	return course, nil
 
 }
 
-func (s service) GetAll(filters Filters, limit, offset int) ([]domain.Course, error) {
-	courses, err := s.repo.GetAll(filters, limit, offset)
+func (s service) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Course, error) {
+	ctx, span := tracer.Start(ctx, "course.GetAll")
+	defer span.End()
+
+	courses, err := s.repo.GetAll(ctx, filters, limit, offset)
 	if err != nil {
-		s.log.Println("Error getting courses:", err)
+		s.log.ErrorContext(ctx, "error getting courses", "error", err)
 		return nil, err
 	}
 	return courses, nil
 }
 
-func (s service) Get(id string) (*domain.Course, error) {
-	course, err := s.repo.Get(id)
+func (s service) Get(ctx context.Context, id string) (*domain.Course, error) {
+	ctx, span := tracer.Start(ctx, "course.Get")
+	defer span.End()
+
+	course, err := s.repo.Get(ctx, id)
 	if err != nil {
-		s.log.Println("Error getting course:", err)
+		s.log.ErrorContext(ctx, "error getting course", "course_id", id, "error", err)
 		return nil, err
 	}
 	return course, nil
 }
 
-func (s service) Update(id string, name, startDate, endDate *string) error {
+func (s service) Update(ctx context.Context, id string, name, startDate, endDate *string, capacity *int, enrollmentOpen, enrollmentClose *string) error {
+	ctx, span := tracer.Start(ctx, "course.Update")
+	defer span.End()
+
 	var startDateParsed, endDateParsed *time.Time
 	if startDate != nil {
 		parsed, err := time.Parse("2006-01-02", *startDate)
 		if err != nil {
-			s.log.Println("Error parsing start date:", err)
-			return err
+			s.log.DebugContext(ctx, "invalid start date", "error", err)
+			return invalidDate("start_date", err)
 		}
 		startDateParsed = &parsed
 	}
 	if endDate != nil {
 		parsed, err := time.Parse("2006-01-02", *endDate)
 		if err != nil {
-			s.log.Println("Error parsing end date:", err)
-			return err
+			s.log.DebugContext(ctx, "invalid end date", "error", err)
+			return invalidDate("end_date", err)
 		}
 		endDateParsed = &parsed
 	}
-	return s.repo.Update(id, name, startDateParsed, endDateParsed)
+	enrollmentOpenParsed, err := parseOptionalDate(enrollmentOpen)
+	if err != nil {
+		s.log.DebugContext(ctx, "invalid enrollment open date", "error", err)
+		return invalidDate("enrollment_open", err)
+	}
+	enrollmentCloseParsed, err := parseOptionalDate(enrollmentClose)
+	if err != nil {
+		s.log.DebugContext(ctx, "invalid enrollment close date", "error", err)
+		return invalidDate("enrollment_close", err)
+	}
+	// El cierre de inscripción por defecto es la fecha de inicio, así que
+	// cambiarla también puede invalidar la ventana.
+	if startDateParsed != nil || enrollmentOpenParsed != nil || enrollmentCloseParsed != nil {
+		current, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if startDateParsed != nil {
+			current.StartDate = *startDateParsed
+		}
+		if enrollmentOpenParsed != nil {
+			current.EnrollmentOpen = enrollmentOpenParsed
+		}
+		if enrollmentCloseParsed != nil {
+			current.EnrollmentClose = enrollmentCloseParsed
+		}
+		if current.EnrollmentOpen != nil && !current.EnrollmentOpen.Before(current.EnrollmentCloseAt()) {
+			return ErrInvalidEnrollmentWindow
+		}
+	}
+	if err := s.repo.Update(ctx, id, name, startDateParsed, endDateParsed, capacity, enrollmentOpenParsed, enrollmentCloseParsed); err != nil {
+		return err
+	}
+	// Si hay más lugares (o el curso pasa a no tener límite) quienes esperan
+	// los ocupan en orden.
+	if capacity != nil && s.promote != nil {
+		if err := s.promote(ctx, id); err != nil {
+			s.log.ErrorContext(ctx, "error promoting waitlist", "course_id", id, "error", err)
+			return err
+		}
+	}
+	return nil
 }
 
-func (s service) Delete(id string) error {
-	return s.repo.Delete(id)
+func (s service) Delete(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "course.Delete")
+	defer span.End()
+
+	return s.repo.Delete(ctx, id)
 }
 
-func (s service) Count(filters Filters) (int, error) {
-	count, err := s.repo.Count(filters)
+func (s service) Count(ctx context.Context, filters Filters) (int, error) {
+	ctx, span := tracer.Start(ctx, "course.Count")
+	defer span.End()
+
+	count, err := s.repo.Count(ctx, filters)
 	if err != nil {
-		s.log.Println("Error counting courses:", err)
+		s.log.ErrorContext(ctx, "error counting courses", "error", err)
 		return 0, err
 	}
 	return count, nil
 }
+
+func (s service) AddPrerequisite(ctx context.Context, id, prerequisiteID string) error {
+	ctx, span := tracer.Start(ctx, "course.AddPrerequisite")
+	defer span.End()
+
+	if id == prerequisiteID {
+		return ErrPrerequisiteCycle
+	}
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return err
+	}
+
+	// Si el curso ya es requisito (directo o indirecto) del nuevo
+	// prerrequisito, agregarlo cerraría un ciclo.
+	closure, err := s.Prerequisites(ctx, prerequisiteID)
+	if err != nil {
+		return err
+	}
+	for _, c := range closure {
+		if c.ID == id {
+			return ErrPrerequisiteCycle
+		}
+	}
+
+	if err := s.repo.AddPrerequisite(ctx, id, prerequisiteID); err != nil {
+		s.log.ErrorContext(ctx, "error adding prerequisite", "course_id", id, "prerequisite_id", prerequisiteID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s service) RemovePrerequisite(ctx context.Context, id, prerequisiteID string) error {
+	ctx, span := tracer.Start(ctx, "course.RemovePrerequisite")
+	defer span.End()
+
+	if err := s.repo.RemovePrerequisite(ctx, id, prerequisiteID); err != nil {
+		s.log.ErrorContext(ctx, "error removing prerequisite", "course_id", id, "prerequisite_id", prerequisiteID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// Prerequisites devuelve la clausura transitiva de prerrequisitos del curso,
+// recorrida en anchura: primero los directos y luego los de cada uno.
+func (s service) Prerequisites(ctx context.Context, id string) ([]domain.Course, error) {
+	ctx, span := tracer.Start(ctx, "course.Prerequisites")
+	defer span.End()
+
+	course, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{id: true}
+	queue := course.Prerequisites
+	closure := []domain.Course{}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next.ID] {
+			continue
+		}
+		seen[next.ID] = true
+
+		prerequisite, err := s.repo.Get(ctx, next.ID)
+		if err != nil {
+			return nil, err
+		}
+		closure = append(closure, *prerequisite)
+		queue = append(queue, prerequisite.Prerequisites...)
+	}
+	return closure, nil
+}
+
+func (s service) AssignInstructor(ctx context.Context, id, userID string) error {
+	ctx, span := tracer.Start(ctx, "course.AssignInstructor")
+	defer span.End()
+
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return err
+	}
+	u, err := s.userSrv.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if u.Role != domain.RoleInstructor && u.Role != domain.RoleAdmin {
+		return ErrCannotTeach
+	}
+	if err := s.repo.AddInstructor(ctx, id, userID); err != nil {
+		s.log.ErrorContext(ctx, "error assigning instructor", "course_id", id, "user_id", userID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s service) UnassignInstructor(ctx context.Context, id, userID string) error {
+	ctx, span := tracer.Start(ctx, "course.UnassignInstructor")
+	defer span.End()
+
+	if err := s.repo.RemoveInstructor(ctx, id, userID); err != nil {
+		s.log.ErrorContext(ctx, "error unassigning instructor", "course_id", id, "user_id", userID, "error", err)
+		return err
+	}
+	return nil
+}
+
+func (s service) IsInstructor(ctx context.Context, id, userID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "course.IsInstructor")
+	defer span.End()
+
+	return s.repo.IsInstructor(ctx, id, userID)
+}
+
+func invalidDate(field string, err error) error {
+	return ErrInvalidDate.WithDetails(map[string]string{"field": field}).Wrap(err)
+}
+
+func parseOptionalDate(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse("2006-01-02", *value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
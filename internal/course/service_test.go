@@ -0,0 +1,245 @@
+package course_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"log/slog"
+	"testing"
+)
+
+// newRepos arma repositorios en memoria con los usuarios teacher, admin y
+// student y los cursos basics, go, que requiere basics, web, que requiere go y
+// basics, y window, con inscripción desde el 1 de febrero de 2030.
+func newRepos(t *testing.T) (course.Repository, user.Repository) {
+	t.Helper()
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	users := user.NewMemoryRepo(logger)
+	for i, u := range []domain.User{
+		{ID: "teacher", Role: domain.RoleInstructor},
+		{ID: "admin", Role: domain.RoleAdmin},
+		{ID: "student", Role: domain.RoleStudent},
+	} {
+		u.Email, u.Phone = u.ID+"@example.com", fmt.Sprint(i)
+		if err := users.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := course.NewMemoryRepo(logger, users)
+	open := date(2030, 2, 1)
+	for _, c := range []domain.Course{
+		{ID: "basics", Name: "Basics"},
+		{ID: "go", Name: "Go"},
+		{ID: "web", Name: "Web"},
+		{ID: "window", Name: "Window", StartDate: date(2030, 3, 1), EnrollmentOpen: &open},
+	} {
+		if err := repo.Create(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range [][2]string{{"go", "basics"}, {"web", "go"}, {"web", "basics"}} {
+		if err := repo.AddPrerequisite(ctx, p[0], p[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return repo, users
+}
+
+func newService(repo course.Repository, users user.Repository, promote course.WaitlistPromoter) course.Service {
+	logger := slog.New(slog.DiscardHandler)
+	return course.NewService(repo, logger, user.NewService(logger, users), promote)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestServiceCreate(t *testing.T) {
+	tests := []struct {
+		name            string
+		start, end      string
+		open, close     *string
+		wantErr         error
+		wantInvalidDate string
+	}{
+		{name: "valid", start: "2030-03-01", end: "2030-06-01", open: ptr("2030-01-01")},
+		{name: "empty enrollment dates", start: "2030-03-01", end: "2030-06-01", open: ptr(""), close: ptr("")},
+		{name: "invalid start date", start: "01/03/2030", end: "2030-06-01", wantErr: course.ErrInvalidDate, wantInvalidDate: "start_date"},
+		{name: "invalid end date", start: "2030-03-01", end: "2030-06", wantErr: course.ErrInvalidDate, wantInvalidDate: "end_date"},
+		{name: "invalid enrollment open", start: "2030-03-01", end: "2030-06-01", open: ptr("x"), wantErr: course.ErrInvalidDate, wantInvalidDate: "enrollment_open"},
+		{name: "invalid enrollment close", start: "2030-03-01", end: "2030-06-01", close: ptr("x"), wantErr: course.ErrInvalidDate, wantInvalidDate: "enrollment_close"},
+		{name: "opens after it closes", start: "2030-03-01", end: "2030-06-01", open: ptr("2030-02-01"), close: ptr("2030-01-01"), wantErr: course.ErrInvalidEnrollmentWindow},
+		{name: "opens after the course starts", start: "2030-03-01", end: "2030-06-01", open: ptr("2030-03-01"), wantErr: course.ErrInvalidEnrollmentWindow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, users := newRepos(t)
+			c, err := newService(repo, users, nil).Create(ctx, "Rust", tt.start, tt.end, 10, tt.open, tt.close)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if count, _ := repo.Count(ctx, course.Filters{}); count != 4 {
+					t.Errorf("Create stored a course after failing: %d courses", count)
+				}
+				if tt.wantInvalidDate != "" {
+					details, _ := apperr.From(err).Details.(map[string]string)
+					if details["field"] != tt.wantInvalidDate {
+						t.Errorf("invalid date details = %v, want field %s", details, tt.wantInvalidDate)
+					}
+				}
+				return
+			}
+			got, err := repo.Get(ctx, c.ID)
+			if err != nil || got.Name != "Rust" || got.StartDate.Format("2006-01-02") != tt.start || got.Capacity != 10 {
+				t.Errorf("stored %+v, %v; want the returned course %+v", got, err, c)
+			}
+		})
+	}
+}
+
+func TestServiceUpdate(t *testing.T) {
+	errPromote := errors.New("promote failed")
+	tests := []struct {
+		name        string
+		id          string
+		start       *string
+		capacity    *int
+		open, close *string
+		promoteErr  error
+		wantErr     error
+		wantUpdated bool
+		wantPromote bool
+	}{
+		{name: "name only", id: "window", wantUpdated: true},
+		{name: "more seats promote the waitlist", id: "window", capacity: ptr(5), wantUpdated: true, wantPromote: true},
+		{name: "promotion fails", id: "window", capacity: ptr(5), promoteErr: errPromote, wantErr: errPromote, wantUpdated: true, wantPromote: true},
+		{name: "start before enrollment opens", id: "window", start: ptr("2030-01-15"), wantErr: course.ErrInvalidEnrollmentWindow},
+		{name: "start after enrollment opens", id: "window", start: ptr("2030-04-01"), wantUpdated: true},
+		{name: "open after the current close", id: "window", open: ptr("2030-03-15"), wantErr: course.ErrInvalidEnrollmentWindow},
+		{name: "close before the current open", id: "window", close: ptr("2030-01-01"), wantErr: course.ErrInvalidEnrollmentWindow},
+		{name: "invalid start date", id: "window", start: ptr("2030-3-1"), wantErr: course.ErrInvalidDate},
+		{name: "missing course", id: "missing", start: ptr("2030-04-01"), wantErr: course.ErrNotFound},
+		{name: "missing course without dates", id: "missing", capacity: ptr(5), wantErr: course.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, users := newRepos(t)
+			var promoted []string
+			promote := func(_ context.Context, id string) error {
+				promoted = append(promoted, id)
+				return tt.promoteErr
+			}
+			err := newService(repo, users, promote).Update(ctx, tt.id, ptr("Renamed"), tt.start, nil, tt.capacity, tt.open, tt.close)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update error = %v, want %v", err, tt.wantErr)
+			}
+			if c, err := repo.Get(ctx, tt.id); err == nil {
+				if updated := c.Name == "Renamed"; updated != tt.wantUpdated {
+					t.Errorf("stored name %q, want updated %v", c.Name, tt.wantUpdated)
+				}
+			}
+			var wantPromoted []string
+			if tt.wantPromote {
+				wantPromoted = []string{tt.id}
+			}
+			if fmt.Sprint(promoted) != fmt.Sprint(wantPromoted) {
+				t.Errorf("promoted = %v, want %v", promoted, wantPromoted)
+			}
+		})
+	}
+
+	t.Run("without a promoter", func(t *testing.T) {
+		repo, users := newRepos(t)
+		if err := newService(repo, users, nil).Update(context.Background(), "go", nil, nil, nil, ptr(5), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		if c, _ := repo.Get(context.Background(), "go"); c.Capacity != 5 {
+			t.Errorf("stored capacity %d, want 5", c.Capacity)
+		}
+	})
+}
+
+func TestServicePrerequisites(t *testing.T) {
+	repo, users := newRepos(t)
+	got, err := newService(repo, users, nil).Prerequisites(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := courseIDs(got); fmt.Sprint(ids) != "[go basics]" {
+		t.Errorf("Prerequisites(web) = %v, want [go basics]", ids)
+	}
+}
+
+func TestServiceAddPrerequisite(t *testing.T) {
+	tests := []struct {
+		name, id, prerequisiteID string
+		wantErr                  error
+	}{
+		{name: "new prerequisite", id: "basics", prerequisiteID: "window"},
+		{name: "itself", id: "go", prerequisiteID: "go", wantErr: course.ErrPrerequisiteCycle},
+		{name: "direct cycle", id: "basics", prerequisiteID: "go", wantErr: course.ErrPrerequisiteCycle},
+		{name: "indirect cycle", id: "basics", prerequisiteID: "web", wantErr: course.ErrPrerequisiteCycle},
+		{name: "missing course", id: "missing", prerequisiteID: "go", wantErr: course.ErrNotFound},
+		{name: "missing prerequisite", id: "go", prerequisiteID: "missing", wantErr: course.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, users := newRepos(t)
+			var want []string
+			if c, err := repo.Get(ctx, tt.id); err == nil {
+				want = courseIDs(c.Prerequisites)
+			}
+			err := newService(repo, users, nil).AddPrerequisite(ctx, tt.id, tt.prerequisiteID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AddPrerequisite error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil {
+				want = append(want, tt.prerequisiteID)
+			}
+			var got []string
+			if c, err := repo.Get(ctx, tt.id); err == nil {
+				got = courseIDs(c.Prerequisites)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("stored prerequisites of %s = %v, want %v", tt.id, got, want)
+			}
+		})
+	}
+}
+
+func TestServiceAssignInstructor(t *testing.T) {
+	tests := []struct {
+		name, id, userID string
+		wantErr          error
+	}{
+		{name: "instructor", id: "go", userID: "teacher"},
+		{name: "admin", id: "go", userID: "admin"},
+		{name: "student", id: "go", userID: "student", wantErr: course.ErrCannotTeach},
+		{name: "missing user", id: "go", userID: "missing", wantErr: user.ErrNotFound},
+		{name: "missing course", id: "missing", userID: "teacher", wantErr: course.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo, users := newRepos(t)
+			srv := newService(repo, users, nil)
+			err := srv.AssignInstructor(ctx, tt.id, tt.userID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AssignInstructor error = %v, want %v", err, tt.wantErr)
+			}
+			if instructs, _ := srv.IsInstructor(ctx, tt.id, tt.userID); instructs != (tt.wantErr == nil) {
+				t.Errorf("IsInstructor(%s, %s) = %v after AssignInstructor", tt.id, tt.userID, instructs)
+			}
+		})
+	}
+}
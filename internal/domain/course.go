@@ -7,14 +7,18 @@ import (
 )
 
 type Course struct {
-	ID        string    `json:"id" gorm:"type:char(36);not null;primaryKey;unique"`
-	Name      string    `json:"name" gorm:"type:char(50);not null"`
-	StartDate time.Time `json:"start_date"`
-	EndDate   time.Time `json:"end_date"`
-	User      *User     `gorm:"-"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt gorm.DeletedAt `gorm:"index"`
+	ID              string     `json:"id" gorm:"type:char(36);not null;primaryKey;unique"`
+	Name            string     `json:"name" gorm:"type:varchar(50);not null"`
+	StartDate       time.Time  `json:"start_date"`
+	EndDate         time.Time  `json:"end_date"`
+	Capacity        int        `json:"capacity" gorm:"not null;default:0"`
+	EnrollmentOpen  *time.Time `json:"enrollment_open"`
+	EnrollmentClose *time.Time `json:"enrollment_close"`
+	Prerequisites   []Course   `json:"prerequisites,omitempty" gorm:"many2many:course_prerequisites;joinForeignKey:CourseID;joinReferences:PrerequisiteID"`
+	Instructors     []User     `json:"instructors,omitempty" gorm:"many2many:course_instructors"`
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`
 }
 
 func (c *Course) BeforeCreate(tx *gorm.DB) (err error) {
@@ -23,3 +27,12 @@ func (c *Course) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// EnrollmentCloseAt devuelve el cierre de inscripciones; si el curso no
+// define uno, las inscripciones cierran cuando empieza el curso.
+func (c *Course) EnrollmentCloseAt() time.Time {
+	if c.EnrollmentClose != nil {
+		return *c.EnrollmentClose
+	}
+	return c.StartDate
+}
@@ -1,25 +1,126 @@
 package domain
 
 import (
+	"encoding/json"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 	"time"
 )
 
+// EnrollmentStatus se guarda en la base como un código de dos caracteres
+// y se expone en JSON con su nombre legible.
+type EnrollmentStatus string
+
+const (
+	EnrollmentPending   EnrollmentStatus = "P"
+	EnrollmentActive    EnrollmentStatus = "A"
+	EnrollmentStudying  EnrollmentStatus = "S"
+	EnrollmentCompleted EnrollmentStatus = "C"
+	EnrollmentWithdrawn EnrollmentStatus = "W"
+	EnrollmentRejected  EnrollmentStatus = "R"
+	EnrollmentWaitlist  EnrollmentStatus = "L"
+)
+
+var enrollmentStatusNames = map[EnrollmentStatus]string{
+	EnrollmentPending:   "pending",
+	EnrollmentActive:    "active",
+	EnrollmentStudying:  "studying",
+	EnrollmentCompleted: "completed",
+	EnrollmentWithdrawn: "withdrawn",
+	EnrollmentRejected:  "rejected",
+	EnrollmentWaitlist:  "waitlisted",
+}
+
+// Tabla de transiciones permitidas; los estados sin entrada son finales.
+var enrollmentTransitions = map[EnrollmentStatus][]EnrollmentStatus{
+	EnrollmentPending:  {EnrollmentActive, EnrollmentRejected, EnrollmentWithdrawn},
+	EnrollmentActive:   {EnrollmentStudying, EnrollmentWithdrawn},
+	EnrollmentStudying: {EnrollmentCompleted, EnrollmentWithdrawn},
+	EnrollmentWaitlist: {EnrollmentRejected, EnrollmentWithdrawn},
+}
+
+// Estados que ocupan un lugar dentro del cupo del curso.
+var seatStatuses = []EnrollmentStatus{
+	EnrollmentPending,
+	EnrollmentActive,
+	EnrollmentStudying,
+	EnrollmentCompleted,
+}
+
+// ParseEnrollmentStatus acepta tanto el código ("P") como el nombre ("pending").
+func ParseEnrollmentStatus(value string) (EnrollmentStatus, bool) {
+	if _, ok := enrollmentStatusNames[EnrollmentStatus(value)]; ok {
+		return EnrollmentStatus(value), true
+	}
+	for status, name := range enrollmentStatusNames {
+		if name == value {
+			return status, true
+		}
+	}
+	return "", false
+}
+
+func (s EnrollmentStatus) String() string {
+	if name, ok := enrollmentStatusNames[s]; ok {
+		return name
+	}
+	return string(s)
+}
+
+func (s EnrollmentStatus) CanTransitionTo(next EnrollmentStatus) bool {
+	for _, allowed := range enrollmentTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsCancelled indica si la inscripción ya no ocupa lugar en el curso.
+func (s EnrollmentStatus) IsCancelled() bool {
+	return s == EnrollmentWithdrawn || s == EnrollmentRejected
+}
+
+// HoldsSeat indica si la inscripción cuenta para la capacidad del curso.
+func (s EnrollmentStatus) HoldsSeat() bool {
+	for _, status := range seatStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+func SeatStatuses() []EnrollmentStatus {
+	return seatStatuses
+}
+
+func (s EnrollmentStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// Active vale true mientras la inscripción no esté cancelada y NULL después,
+// así el índice único idx_enrollment_user_course solo aplica a las vigentes.
 type Enrollment struct {
-	ID        string     `json:"id" gorm:"type:char(36);not null;primary_key;unique_index"`
-	UserID    string     `json:"user_id,omitempty" gorm:"type:char(36)"`
-	User      *User      `json:"user,omitempty"`
-	CourseID  string     `json:"course_id" gorm:"type:char(36);not null"`
-	Course    *Course    `json:"course,omitempty"`
-	Status    string     `json:"status" gorm:"type:char(2)"`
-	CreatedAt *time.Time `json:"-"`
-	UpdatedAt *time.Time `json:"-"`
+	ID               string           `json:"id" gorm:"type:char(36);not null;primary_key;unique_index"`
+	UserID           string           `json:"user_id,omitempty" gorm:"type:char(36);uniqueIndex:idx_enrollment_user_course"`
+	User             *User            `json:"user,omitempty"`
+	CourseID         string           `json:"course_id" gorm:"type:char(36);not null;uniqueIndex:idx_enrollment_user_course"`
+	Course           *Course          `json:"course,omitempty"`
+	Status           EnrollmentStatus `json:"status" gorm:"type:varchar(2)"`
+	Active           *bool            `json:"-" gorm:"uniqueIndex:idx_enrollment_user_course"`
+	WaitlistPosition *int             `json:"waitlist_position,omitempty"`
+	CreatedAt        *time.Time       `json:"-"`
+	UpdatedAt        *time.Time       `json:"-"`
 }
 
 func (c *Enrollment) BeforeCreate(tx *gorm.DB) (err error) {
 	if c.ID == "" {
 		c.ID = uuid.New().String()
 	}
+	if c.Active == nil && !c.Status.IsCancelled() {
+		active := true
+		c.Active = &active
+	}
 	return
 }
@@ -0,0 +1,26 @@
+package domain
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// RefreshToken guarda solo el hash del token; el valor en claro se entrega
+// una única vez al cliente.
+type RefreshToken struct {
+	ID         string    `gorm:"type:char(36);primaryKey"`
+	UserID     string    `gorm:"type:char(36);not null;index"`
+	TokenHash  string    `gorm:"type:char(64);not null;uniqueIndex"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	ReplacedBy *string `gorm:"type:char(36)"`
+	CreatedAt  time.Time
+}
+
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	return
+}
@@ -6,12 +6,27 @@ import (
 	"time"
 )
 
+type Role string
+
+const (
+	RoleAdmin      Role = "admin"
+	RoleInstructor Role = "instructor"
+	RoleStudent    Role = "student"
+)
+
+func (r Role) Valid() bool {
+	return r == RoleAdmin || r == RoleInstructor || r == RoleStudent
+}
+
 type User struct {
 	ID        string  `gorm:"type:char(36);primaryKey"`
-	FirstName string  `json:"first_name" gorm:"type:char(50);not null"`
-	LastName  string  `json:"last_name" gorm:"type:char(50);not null"`
-	Email     string  `json:"email" gorm:"type:char(50);not null; unique"`
-	Phone     string  `json:"phone" gorm:"type:char(11);not null; unique"`
+	FirstName string  `json:"first_name" gorm:"type:varchar(50);not null"`
+	LastName  string  `json:"last_name" gorm:"type:varchar(50);not null"`
+	Email     string  `json:"email" gorm:"type:varchar(50);not null; unique"`
+	Phone     string  `json:"phone" gorm:"type:varchar(11);not null; unique"`
+	Password  string  `json:"-" gorm:"type:varchar(255);not null;default:''"`
+	Role      Role    `json:"role" gorm:"type:varchar(20);not null;default:'student'"`
+	Locale    string  `json:"locale" gorm:"type:varchar(5);not null;default:''"`
 	Course    *Course `gorm:"-"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
@@ -22,5 +37,8 @@ func (u *User) BeforeCreate(tx *gorm.DB) (err error) {
 	if u.ID == "" {
 		u.ID = uuid.New().String()
 	}
+	if u.Role == "" {
+		u.Role = RoleStudent
+	}
 	return
 }
@@ -2,31 +2,50 @@ package enrollment
 
 import (
 	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/policy"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
 	"github.com/raminpz/gocourse_web/pkg/meta"
+	"github.com/raminpz/gocourse_web/pkg/response"
 	"net/http"
+	"strconv"
 )
 
 type (
 	Controller func(w http.ResponseWriter, r *http.Request)
 	Endpoints  struct {
-		Create Controller
+		Create     Controller
+		Get        Controller
+		GetAll     Controller
+		Update     Controller
+		Transition Controller
+		Delete     Controller
+		Waitlist   Controller
 	}
 	CreateReq struct {
 		CourseID string `json:"course_id"`
 		UserID   string `json:"user_id"`
 	}
 
-	Response struct {
-		Status int         `json:"status"`
-		Data   interface{} `json:"data,omitempty"`
-		Err    string      `json:"error,omitempty"`
-		Meta   *meta.Meta  `json:"meta,omitempty"`
+	UpdateReq struct {
+		Status *string `json:"status"`
+	}
+
+	TransitionReq struct {
+		Status string `json:"status"`
 	}
 )
 
 func MakeEndpoints(s Service) Endpoints {
 	return Endpoints{
-		Create: makeCreateEndpoint(s),
+		Create:     makeCreateEndpoint(s),
+		Get:        makeGetEndpoint(s),
+		GetAll:     makeGetAllEndpoint(s),
+		Update:     makeUpdateEndpoint(s),
+		Transition: makeTransitionEndpoint(s),
+		Delete:     makeDeleteEndpoint(s),
+		Waitlist:   makeWaitlistEndpoint(s),
 	}
 }
 
@@ -34,28 +53,225 @@ func makeCreateEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req CreateReq
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "Invalid request format"})
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
 			return
 		}
+		// Sin user_id explícito se inscribe al usuario autenticado.
+		if req.UserID == "" {
+			req.UserID = policy.ActorFrom(r.Context()).ID
+		}
 		if req.UserID == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "UserID are is required"})
+			response.Error(w, r, apperr.Validation("user_id_required", "user id is required"))
 			return
-
 		}
 		if req.CourseID == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "CourseID is required"})
+			response.Error(w, r, apperr.Validation("course_id_required", "course id is required"))
+			return
+		}
+		if !authorize(w, r, policy.CreateEnrollment, policy.Resource{OwnerID: req.UserID}) {
 			return
 		}
-		enroll, err := s.Create(req.UserID, req.CourseID)
+		enroll, err := s.Create(r.Context(), req.UserID, req.CourseID)
 		if err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: err.Error()})
+			response.Error(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: enroll})
+		response.JSON(w, r, response.OK(enroll))
 	}
 
 }
+
+func makeGetAllEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := r.URL.Query()
+
+		filters := Filters{
+			UserID:   v.Get("user_id"),
+			CourseID: v.Get("course_id"),
+		}
+		if status := v.Get("status"); status != "" {
+			parsed, ok := domain.ParseEnrollmentStatus(status)
+			if !ok {
+				response.Error(w, r, ErrInvalidStatus)
+				return
+			}
+			filters.Status = parsed
+		}
+
+		// Quien no es admin solo ve sus inscripciones o las de un curso que dicta.
+		actor := policy.ActorFrom(r.Context())
+		if actor.Role != domain.RoleAdmin && filters.UserID == "" && filters.CourseID == "" {
+			filters.UserID = actor.ID
+		}
+		res := policy.Resource{OwnerID: filters.UserID}
+		if filters.CourseID != "" {
+			res.Instructs = instructs(s, r, filters.CourseID)
+		}
+		if !authorize(w, r, policy.ReadEnrollment, res) {
+			return
+		}
+
+		limit, _ := strconv.Atoi(v.Get("limit"))
+		page, _ := strconv.Atoi(v.Get("page"))
+
+		count, err := s.Count(r.Context(), filters)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		meta, err := meta.New(page, limit, count)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		enrollments, err := s.GetAll(r.Context(), filters, meta.Limit(), meta.Offset())
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.Page(enrollments, meta))
+	}
+}
+
+func makeGetEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := mux.Vars(r)
+		id := path["id"]
+		enroll, _, ok := load(w, r, s, id)
+		if !ok {
+			return
+		}
+		response.JSON(w, r, response.OK(enroll))
+	}
+}
+
+func makeUpdateEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req UpdateReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
+			return
+		}
+		if req.Status == nil || *req.Status == "" {
+			response.Error(w, r, apperr.Validation("status_required", "status is required"))
+			return
+		}
+
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorizeUpdate(w, r, s, id) {
+			return
+		}
+
+		if err := s.Update(r.Context(), id, req.Status); err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK("success"))
+	}
+}
+
+func makeTransitionEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req TransitionReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
+			return
+		}
+		if req.Status == "" {
+			response.Error(w, r, apperr.Validation("status_required", "status is required"))
+			return
+		}
+
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorizeUpdate(w, r, s, id) {
+			return
+		}
+
+		enroll, err := s.Transition(r.Context(), id, req.Status)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK(enroll))
+	}
+}
+
+func makeDeleteEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.DeleteEnrollment, policy.Resource{}) {
+			return
+		}
+
+		if err := s.Delete(r.Context(), id); err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK("success"))
+	}
+}
+
+func makeWaitlistEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := mux.Vars(r)
+		id := path["id"]
+		if !authorize(w, r, policy.ReadEnrollment, policy.Resource{Instructs: instructs(s, r, id)}) {
+			return
+		}
+
+		enrollments, err := s.Waitlist(r.Context(), id)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+		response.JSON(w, r, response.OK(enrollments))
+	}
+}
+
+func authorize(w http.ResponseWriter, r *http.Request, action policy.Action, res policy.Resource) bool {
+	if err := policy.Authorize(policy.ActorFrom(r.Context()), action, res); err != nil {
+		response.Error(w, r, err)
+		return false
+	}
+	return true
+}
+
+// load carga la inscripción id si quien hace la petición puede verla. Si no
+// puede, responde lo mismo que si no existiera para no revelar qué ids existen.
+func load(w http.ResponseWriter, r *http.Request, s Service, id string) (*domain.Enrollment, policy.Resource, bool) {
+	enroll, err := s.Get(r.Context(), id)
+	if err != nil {
+		response.Error(w, r, err)
+		return nil, policy.Resource{}, false
+	}
+	res := enrollmentResource(s, r, enroll)
+	if policy.Authorize(policy.ActorFrom(r.Context()), policy.ReadEnrollment, res) != nil {
+		response.Error(w, r, ErrNotFound)
+		return nil, policy.Resource{}, false
+	}
+	return enroll, res, true
+}
+
+// authorizeUpdate carga la inscripción para saber a qué curso pertenece antes
+// de decidir si quien hace la petición puede cambiar su estado.
+func authorizeUpdate(w http.ResponseWriter, r *http.Request, s Service, id string) bool {
+	_, res, ok := load(w, r, s, id)
+	return ok && authorize(w, r, policy.UpdateEnrollment, res)
+}
+
+func enrollmentResource(s Service, r *http.Request, enroll *domain.Enrollment) policy.Resource {
+	return policy.Resource{
+		OwnerID:   enroll.UserID,
+		Instructs: instructs(s, r, enroll.CourseID),
+	}
+}
+
+// instructs indica si quien hace la petición dicta el curso courseID.
+func instructs(s Service, r *http.Request, courseID string) bool {
+	ok, err := s.Instructs(r.Context(), policy.ActorFrom(r.Context()).ID, courseID)
+	return err == nil && ok
+}
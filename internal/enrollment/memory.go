@@ -0,0 +1,358 @@
+package enrollment
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryRepo guarda las inscripciones en memoria con la misma semántica que
+// repo: cupo y lista de espera por curso, una sola inscripción vigente por
+// usuario y curso, y orden por fecha de creación descendente. El mutex hace
+// las veces del bloqueo de la fila del curso y, como en una transacción, nada
+// se modifica hasta que no queda nada que pueda fallar. Cursos y usuarios se
+// leen de courses y users.
+type memoryRepo struct {
+	log         *slog.Logger
+	users       user.Repository
+	courses     course.Repository
+	mu          sync.RWMutex
+	enrollments map[string]domain.Enrollment
+}
+
+func NewMemoryRepo(logger *slog.Logger, users user.Repository, courses course.Repository) Repository {
+	return &memoryRepo{
+		log:         logger,
+		users:       users,
+		courses:     courses,
+		enrollments: make(map[string]domain.Enrollment),
+	}
+}
+
+// Create inscribe al usuario o lo agrega al final de la lista de espera si el
+// curso no tiene cupo.
+func (r *memoryRepo) Create(ctx context.Context, enroll *domain.Enrollment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.create(ctx, enroll); err != nil {
+		r.log.ErrorContext(ctx, "error creating enrollment", "error", err)
+		return err
+	}
+	r.log.DebugContext(ctx, "enrollment inserted", "enrollment_id", enroll.ID)
+	return nil
+}
+
+func (r *memoryRepo) create(ctx context.Context, enroll *domain.Enrollment) error {
+	c, err := r.lockCourse(ctx, enroll.CourseID)
+	if err != nil {
+		return err
+	}
+
+	if enroll.ID == "" {
+		enroll.ID = uuid.New().String()
+	}
+	if enroll.Active == nil && !enroll.Status.IsCancelled() {
+		active := true
+		enroll.Active = &active
+	}
+	if _, ok := r.enrollments[enroll.ID]; ok {
+		return apperr.DB(gorm.ErrDuplicatedKey, ErrNotFound)
+	}
+	if isActive(*enroll) {
+		for _, e := range r.enrollments {
+			if isActive(e) && e.UserID == enroll.UserID && e.CourseID == enroll.CourseID {
+				return apperr.DB(gorm.ErrDuplicatedKey, ErrNotFound)
+			}
+		}
+	}
+
+	// Como en repo, quienes esperan ocupan los lugares libres antes que la
+	// nueva inscripción.
+	r.promote(c)
+	if c.Capacity > 0 && enroll.Status.HoldsSeat() && r.countSeats(enroll.CourseID) >= c.Capacity {
+		last := 0
+		for _, e := range r.enrollments {
+			if e.CourseID == enroll.CourseID && e.Status == domain.EnrollmentWaitlist && e.WaitlistPosition != nil && *e.WaitlistPosition > last {
+				last = *e.WaitlistPosition
+			}
+		}
+		position := last + 1
+		enroll.Status = domain.EnrollmentWaitlist
+		enroll.WaitlistPosition = &position
+	}
+
+	now := time.Now()
+	if enroll.CreatedAt == nil {
+		enroll.CreatedAt = &now
+	}
+	if enroll.UpdatedAt == nil {
+		enroll.UpdatedAt = &now
+	}
+
+	stored := *enroll
+	stored.User = nil
+	stored.Course = nil
+	r.enrollments[enroll.ID] = stored
+	return nil
+}
+
+func (r *memoryRepo) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Enrollment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	enrollments := r.filter(filters)
+	sort.Slice(enrollments, func(i, j int) bool {
+		// A igual fecha se ordena por ID para que las páginas sean estables.
+		a, b := enrollments[i].CreatedAt, enrollments[j].CreatedAt
+		if a != nil && b != nil && !a.Equal(*b) {
+			return a.After(*b)
+		}
+		return enrollments[i].ID < enrollments[j].ID
+	})
+	// Igual que Limit y Offset de gorm: un límite negativo no limita y 0 no
+	// devuelve nada.
+	if offset > 0 {
+		if offset >= len(enrollments) {
+			return []domain.Enrollment{}, nil
+		}
+		enrollments = enrollments[offset:]
+	}
+	if limit >= 0 && limit < len(enrollments) {
+		enrollments = enrollments[:limit]
+	}
+	return enrollments, nil
+}
+
+// Get devuelve la inscripción con su usuario y su curso; como el Preload,
+// quedan vacíos si fueron borrados.
+func (r *memoryRepo) Get(ctx context.Context, id string) (*domain.Enrollment, error) {
+	r.mu.RLock()
+	enroll, ok := r.enrollments[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	u, err := r.users.GetByID(ctx, enroll.UserID)
+	switch {
+	case err == nil:
+		enroll.User = u
+	case !errors.Is(err, user.ErrNotFound):
+		return nil, err
+	}
+	c, err := r.courses.Get(ctx, enroll.CourseID)
+	switch {
+	case err == nil:
+		c.Prerequisites = nil
+		c.Instructors = nil
+		enroll.Course = c
+	case !errors.Is(err, course.ErrNotFound):
+		return nil, err
+	}
+	return &enroll, nil
+}
+
+func (r *memoryRepo) GetActive(ctx context.Context, userID, courseID string) (*domain.Enrollment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.enrollments {
+		if isActive(e) && e.UserID == userID && e.CourseID == courseID {
+			return &e, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRepo) GetWaitlist(ctx context.Context, courseID string) ([]domain.Enrollment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.waitlist(courseID), nil
+}
+
+// UpdateStatus cambia el estado solo si la inscripción sigue en el estado
+// from; si con el cambio se libera un lugar, promueve al siguiente en espera.
+func (r *memoryRepo) UpdateStatus(ctx context.Context, id string, from, to domain.EnrollmentStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enroll, ok := r.enrollments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	c, err := r.lockCourse(ctx, enroll.CourseID)
+	if err != nil {
+		return err
+	}
+	if enroll.Status != from {
+		return ErrStatusChanged
+	}
+
+	updated := enroll
+	updated.Status = to
+	if to.IsCancelled() {
+		updated.Active = nil
+	}
+	if to != domain.EnrollmentWaitlist {
+		updated.WaitlistPosition = nil
+	}
+	now := time.Now()
+	updated.UpdatedAt = &now
+	r.enrollments[id] = updated
+
+	r.release(c, enroll, to)
+	return nil
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	enroll, ok := r.enrollments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	c, err := r.lockCourse(ctx, enroll.CourseID)
+	if err != nil {
+		return err
+	}
+	delete(r.enrollments, id)
+
+	r.release(c, enroll, domain.EnrollmentWithdrawn)
+	return nil
+}
+
+func (r *memoryRepo) Promote(ctx context.Context, courseID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, err := r.lockCourse(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	r.promote(c)
+	return nil
+}
+
+func (r *memoryRepo) Count(ctx context.Context, filters Filters) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.filter(filters)), nil
+}
+
+func (r *memoryRepo) filter(filters Filters) []domain.Enrollment {
+	enrollments := []domain.Enrollment{}
+	for _, e := range r.enrollments {
+		if filters.UserID != "" && e.UserID != filters.UserID {
+			continue
+		}
+		if filters.CourseID != "" && e.CourseID != filters.CourseID {
+			continue
+		}
+		if filters.Status != "" && e.Status != filters.Status {
+			continue
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments
+}
+
+func (r *memoryRepo) waitlist(courseID string) []domain.Enrollment {
+	enrollments := r.filter(Filters{CourseID: courseID, Status: domain.EnrollmentWaitlist})
+	sort.Slice(enrollments, func(i, j int) bool {
+		return position(enrollments[i]) < position(enrollments[j])
+	})
+	return enrollments
+}
+
+func (r *memoryRepo) countSeats(courseID string) int {
+	seats := 0
+	for _, e := range r.enrollments {
+		if e.CourseID == courseID && e.Status.HoldsSeat() {
+			seats++
+		}
+	}
+	return seats
+}
+
+// lockCourse lee el curso aunque esté borrado, como lockCourse en repo.
+func (r *memoryRepo) lockCourse(ctx context.Context, courseID string) (*domain.Course, error) {
+	c, err := r.courses.GetUnscoped(ctx, courseID)
+	if errors.Is(err, course.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	return c, err
+}
+
+// release ajusta la lista de espera de c cuando enroll deja su estado
+// anterior, igual que la función release del repositorio con base de datos.
+// Debe llamarse con r.mu tomado.
+func (r *memoryRepo) release(c *domain.Course, enroll domain.Enrollment, to domain.EnrollmentStatus) {
+	if enroll.Status == domain.EnrollmentWaitlist && to != domain.EnrollmentWaitlist && enroll.WaitlistPosition != nil {
+		r.shiftWaitlist(enroll.CourseID, *enroll.WaitlistPosition)
+	}
+	if enroll.Status.HoldsSeat() && !to.HoldsSeat() {
+		r.promote(c)
+	}
+}
+
+// promote pasa a pendiente a los primeros de la lista de espera mientras c
+// tenga lugares disponibles.
+func (r *memoryRepo) promote(c *domain.Course) {
+	for {
+		if c.Capacity > 0 && r.countSeats(c.ID) >= c.Capacity {
+			return
+		}
+		waitlist := r.waitlist(c.ID)
+		if len(waitlist) == 0 {
+			return
+		}
+
+		next := waitlist[0]
+		promoted := next
+		promoted.Status = domain.EnrollmentPending
+		promoted.WaitlistPosition = nil
+		now := time.Now()
+		promoted.UpdatedAt = &now
+		r.enrollments[next.ID] = promoted
+		if next.WaitlistPosition != nil {
+			r.shiftWaitlist(c.ID, *next.WaitlistPosition)
+		}
+	}
+}
+
+func (r *memoryRepo) shiftWaitlist(courseID string, after int) {
+	now := time.Now()
+	for id, e := range r.enrollments {
+		if e.CourseID == courseID && e.Status == domain.EnrollmentWaitlist && e.WaitlistPosition != nil && *e.WaitlistPosition > after {
+			shifted := *e.WaitlistPosition - 1
+			e.WaitlistPosition = &shifted
+			e.UpdatedAt = &now
+			r.enrollments[id] = e
+		}
+	}
+}
+
+// isActive replica la condición del índice único idx_enrollment_user_course.
+func isActive(e domain.Enrollment) bool {
+	return e.Active != nil && *e.Active
+}
+
+func position(e domain.Enrollment) int {
+	if e.WaitlistPosition == nil {
+		return 0
+	}
+	return *e.WaitlistPosition
+}
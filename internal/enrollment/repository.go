@@ -1,34 +1,330 @@
 package enrollment
 
 import (
+	"context"
+	"errors"
 	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
 	"gorm.io/gorm"
-	"log"
+	"gorm.io/gorm/clause"
+	"log/slog"
 )
 
 type (
 	Repository interface {
-		Create(enroll *domain.Enrollment) error
+		Create(ctx context.Context, enroll *domain.Enrollment) error
+		GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Enrollment, error)
+		Get(ctx context.Context, id string) (*domain.Enrollment, error)
+		GetActive(ctx context.Context, userID, courseID string) (*domain.Enrollment, error)
+		GetWaitlist(ctx context.Context, courseID string) ([]domain.Enrollment, error)
+		UpdateStatus(ctx context.Context, id string, from, to domain.EnrollmentStatus) error
+		Delete(ctx context.Context, id string) error
+		Count(ctx context.Context, filters Filters) (int, error)
+		Promote(ctx context.Context, courseID string) error
 	}
 
 	repo struct {
 		db  *gorm.DB
-		log *log.Logger
+		log *slog.Logger
 	}
 )
 
-func NewRepo(db *gorm.DB, logger *log.Logger) Repository {
+// ErrStatusChanged se devuelve cuando otra petición modificó el estado de la
+// inscripción antes de que se aplicara el cambio.
+var ErrStatusChanged = apperr.Conflict("status_changed", "enrollment status was changed by another request")
+
+func NewRepo(db *gorm.DB, logger *slog.Logger) Repository {
 	return &repo{
 		db:  db,
 		log: logger,
 	}
 }
 
-func (r *repo) Create(enroll *domain.Enrollment) error {
-	if err := r.db.Create(enroll).Error; err != nil {
-		r.log.Println("error: %v", err)
-		return err
+// Create inscribe al usuario o lo agrega al final de la lista de espera si el
+// curso no tiene cupo. La fila del curso queda bloqueada durante la transacción
+// para que inscripciones y cancelaciones concurrentes no excedan la capacidad.
+// Antes de contar los lugares se promueve a quienes esperan, así una nueva
+// inscripción nunca toma un lugar que le corresponde a la lista de espera.
+func (r *repo) Create(ctx context.Context, enroll *domain.Enrollment) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		course, err := lockCourse(tx, enroll.CourseID)
+		if err != nil {
+			return err
+		}
+		if err := promote(tx, enroll.CourseID); err != nil {
+			return err
+		}
+
+		if course.Capacity > 0 && enroll.Status.HoldsSeat() {
+			seats, err := countSeats(tx, enroll.CourseID)
+			if err != nil {
+				return err
+			}
+			if seats >= int64(course.Capacity) {
+				var last int
+				if err := tx.Model(&domain.Enrollment{}).
+					Where("course_id = ? AND status = ?", enroll.CourseID, domain.EnrollmentWaitlist).
+					Select("COALESCE(MAX(waitlist_position), 0)").
+					Scan(&last).Error; err != nil {
+					return err
+				}
+				position := last + 1
+				enroll.Status = domain.EnrollmentWaitlist
+				enroll.WaitlistPosition = &position
+			}
+		}
+
+		return tx.Create(enroll).Error
+	})
+	if err != nil {
+		r.log.ErrorContext(ctx, "error creating enrollment", "error", err)
+		return apperr.DB(err, ErrNotFound)
 	}
-	r.log.Println("enrollment created with id:", enroll.ID)
+	r.log.DebugContext(ctx, "enrollment inserted", "enrollment_id", enroll.ID)
 	return nil
 }
+
+func (r *repo) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Enrollment, error) {
+	var enrollments []domain.Enrollment
+	tx := r.db.WithContext(ctx).Model(&enrollments)
+	tx = applyFilters(tx, filters)
+	tx = tx.Limit(limit).Offset(offset)
+	result := tx.Order("created_at desc").Find(&enrollments)
+	if result.Error != nil {
+		return nil, apperr.DB(result.Error, ErrNotFound)
+	}
+	return enrollments, nil
+}
+
+func (r *repo) Get(ctx context.Context, id string) (*domain.Enrollment, error) {
+	enroll := domain.Enrollment{ID: id}
+	result := r.db.WithContext(ctx).Preload("User").Preload("Course").First(&enroll)
+	if result.Error != nil {
+		return nil, apperr.DB(result.Error, ErrNotFound)
+	}
+	return &enroll, nil
+}
+
+func (r *repo) GetActive(ctx context.Context, userID, courseID string) (*domain.Enrollment, error) {
+	var enroll domain.Enrollment
+	result := r.db.WithContext(ctx).Where("user_id = ? AND course_id = ? AND active = ?", userID, courseID, true).First(&enroll)
+	if result.Error != nil {
+		return nil, apperr.DB(result.Error, ErrNotFound)
+	}
+	return &enroll, nil
+}
+
+func (r *repo) GetWaitlist(ctx context.Context, courseID string) ([]domain.Enrollment, error) {
+	var enrollments []domain.Enrollment
+	result := r.db.WithContext(ctx).Where("course_id = ? AND status = ?", courseID, domain.EnrollmentWaitlist).
+		Order("waitlist_position asc").
+		Find(&enrollments)
+	if result.Error != nil {
+		return nil, apperr.DB(result.Error, ErrNotFound)
+	}
+	return enrollments, nil
+}
+
+// UpdateStatus cambia el estado solo si la inscripción sigue en el estado
+// from; si con el cambio se libera un lugar, promueve al siguiente en espera.
+func (r *repo) UpdateStatus(ctx context.Context, id string, from, to domain.EnrollmentStatus) error {
+	courseID, err := r.courseOf(ctx, id)
+	if err != nil {
+		return apperr.DB(err, ErrNotFound)
+	}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		enroll, err := lockEnrollment(tx, courseID, id)
+		if err != nil {
+			return err
+		}
+
+		values := map[string]interface{}{"status": to}
+		if to.IsCancelled() {
+			values["active"] = nil
+		}
+		if to != domain.EnrollmentWaitlist {
+			values["waitlist_position"] = nil
+		}
+		result := tx.Model(&domain.Enrollment{}).Where("id = ? AND status = ?", id, from).Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrStatusChanged
+		}
+
+		return release(tx, enroll, to)
+	})
+	return apperr.DB(err, ErrNotFound)
+}
+
+func (r *repo) Delete(ctx context.Context, id string) error {
+	courseID, err := r.courseOf(ctx, id)
+	if err != nil {
+		return apperr.DB(err, ErrNotFound)
+	}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		enroll, err := lockEnrollment(tx, courseID, id)
+		if err != nil {
+			return err
+		}
+
+		result := tx.Delete(&domain.Enrollment{ID: id})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return release(tx, enroll, domain.EnrollmentWithdrawn)
+	})
+	return apperr.DB(err, ErrNotFound)
+}
+
+// Promote pasa a pendiente a los primeros de la lista de espera mientras el
+// curso tenga lugares; se usa cuando cambia la capacidad del curso.
+func (r *repo) Promote(ctx context.Context, courseID string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockCourse(tx, courseID); err != nil {
+			return err
+		}
+		return promote(tx, courseID)
+	})
+	if err != nil {
+		r.log.ErrorContext(ctx, "error promoting waitlist", "course_id", courseID, "error", err)
+		return apperr.DB(err, ErrNotFound)
+	}
+	return nil
+}
+
+func (r *repo) Count(ctx context.Context, filters Filters) (int, error) {
+	var count int64
+	tx := r.db.WithContext(ctx).Model(&domain.Enrollment{})
+	tx = applyFilters(tx, filters)
+	if err := tx.Count(&count).Error; err != nil {
+		return 0, apperr.DB(err, ErrNotFound)
+	}
+	return int(count), nil
+}
+
+func applyFilters(tx *gorm.DB, filters Filters) *gorm.DB {
+	if filters.UserID != "" {
+		tx = tx.Where("user_id = ?", filters.UserID)
+	}
+	if filters.CourseID != "" {
+		tx = tx.Where("course_id = ?", filters.CourseID)
+	}
+	if filters.Status != "" {
+		tx = tx.Where("status = ?", filters.Status)
+	}
+	return tx
+}
+
+// courseOf devuelve el curso de la inscripción id. Se consulta fuera de la
+// transacción que la modifica (una inscripción nunca cambia de curso) para
+// que la primera lectura de esa transacción sea el bloqueo del curso.
+func (r *repo) courseOf(ctx context.Context, id string) (string, error) {
+	enroll := domain.Enrollment{ID: id}
+	if err := r.db.WithContext(ctx).Select("course_id").First(&enroll).Error; err != nil {
+		return "", err
+	}
+	return enroll.CourseID, nil
+}
+
+// lockEnrollment bloquea el curso y recién después lee la inscripción id. En
+// MySQL con REPEATABLE READ la instantánea de la transacción se fija en la
+// primera lectura sin bloqueo; leer la inscripción antes del bloqueo haría que
+// countSeats y la lista de espera no vieran lo que otras transacciones
+// confirmaron mientras se esperaba el curso.
+func lockEnrollment(tx *gorm.DB, courseID, id string) (*domain.Enrollment, error) {
+	if _, err := lockCourse(tx, courseID); err != nil {
+		return nil, err
+	}
+	enroll := domain.Enrollment{ID: id}
+	if err := tx.First(&enroll).Error; err != nil {
+		return nil, err
+	}
+	return &enroll, nil
+}
+
+func lockCourse(tx *gorm.DB, courseID string) (*domain.Course, error) {
+	course := domain.Course{ID: courseID}
+	if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&course).Error; err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+func countSeats(tx *gorm.DB, courseID string) (int64, error) {
+	var seats int64
+	err := tx.Model(&domain.Enrollment{}).
+		Where("course_id = ? AND status IN ?", courseID, domain.SeatStatuses()).
+		Count(&seats).Error
+	return seats, err
+}
+
+// release ajusta la lista de espera del curso cuando enroll deja su estado
+// anterior: cierra el hueco que deja en la lista o promueve a quien sigue
+// si liberó un lugar. Debe llamarse con la fila del curso bloqueada.
+func release(tx *gorm.DB, enroll *domain.Enrollment, to domain.EnrollmentStatus) error {
+	if enroll.Status == domain.EnrollmentWaitlist && to != domain.EnrollmentWaitlist && enroll.WaitlistPosition != nil {
+		if err := shiftWaitlist(tx, enroll.CourseID, *enroll.WaitlistPosition); err != nil {
+			return err
+		}
+	}
+	if enroll.Status.HoldsSeat() && !to.HoldsSeat() {
+		return promote(tx, enroll.CourseID)
+	}
+	return nil
+}
+
+// promote pasa a pendiente a los primeros de la lista de espera mientras
+// el curso tenga lugares disponibles.
+func promote(tx *gorm.DB, courseID string) error {
+	course := domain.Course{ID: courseID}
+	if err := tx.Unscoped().First(&course).Error; err != nil {
+		return err
+	}
+
+	for {
+		if course.Capacity > 0 {
+			seats, err := countSeats(tx, courseID)
+			if err != nil {
+				return err
+			}
+			if seats >= int64(course.Capacity) {
+				return nil
+			}
+		}
+
+		var next domain.Enrollment
+		err := tx.Where("course_id = ? AND status = ?", courseID, domain.EnrollmentWaitlist).
+			Order("waitlist_position asc").
+			First(&next).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&domain.Enrollment{}).Where("id = ?", next.ID).Updates(map[string]interface{}{
+			"status":            domain.EnrollmentPending,
+			"waitlist_position": nil,
+		}).Error; err != nil {
+			return err
+		}
+		if next.WaitlistPosition != nil {
+			if err := shiftWaitlist(tx, courseID, *next.WaitlistPosition); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func shiftWaitlist(tx *gorm.DB, courseID string, after int) error {
+	return tx.Model(&domain.Enrollment{}).
+		Where("course_id = ? AND status = ? AND waitlist_position > ?", courseID, domain.EnrollmentWaitlist, after).
+		Update("waitlist_position", gorm.Expr("waitlist_position - 1")).Error
+}
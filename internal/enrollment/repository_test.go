@@ -0,0 +1,356 @@
+package enrollment_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"github.com/raminpz/gocourse_web/internal/testdb"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// repos son los repositorios que usa la suite; comparten almacenamiento.
+type repos struct {
+	enrollments enrollment.Repository
+	users       user.Repository
+	courses     course.Repository
+}
+
+func TestRepository(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	t.Run("memory", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) repos {
+			users := user.NewMemoryRepo(logger)
+			courses := course.NewMemoryRepo(logger, users)
+			return repos{enrollment.NewMemoryRepo(logger, users, courses), users, courses}
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) repos {
+			db := testdb.Open(t)
+			return repos{enrollment.NewRepo(db, logger), user.NewRepo(logger, db), course.NewRepo(db, logger)}
+		})
+	})
+}
+
+// TestRepositoryLocksCourseFirst comprueba que UpdateStatus y Delete leen la
+// inscripción recién después de bloquear el curso, dentro de la transacción.
+func TestRepositoryLocksCourseFirst(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	db := testdb.Open(t)
+	users, courses, enrollments := user.NewRepo(logger, db), course.NewRepo(db, logger), enrollment.NewRepo(db, logger)
+
+	var tables []string
+	if err := db.Callback().Query().After("gorm:query").Register("test:tables", func(tx *gorm.DB) {
+		tables = append(tables, tx.Statement.Table)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := createCourse(t, courses, 0)
+	for i, tt := range []struct {
+		name   string
+		change func(id string) error
+	}{
+		{"update status", func(id string) error {
+			return enrollments.UpdateStatus(ctx, id, domain.EnrollmentPending, domain.EnrollmentActive)
+		}},
+		{"delete", func(id string) error { return enrollments.Delete(ctx, id) }},
+	} {
+		e := enroll(t, enrollments, createUser(t, users, i).ID, c.ID)
+		tables = nil
+		if err := tt.change(e.ID); err != nil {
+			t.Fatal(err)
+		}
+		// La primera consulta busca el curso fuera de la transacción.
+		if len(tables) < 3 || fmt.Sprint(tables[:3]) != "[enrollments courses enrollments]" {
+			t.Errorf("%s queried %v, want the course locked before the enrollment is read", tt.name, tables)
+		}
+	}
+}
+
+// RepositorySuite comprueba el contrato de enrollment.Repository, incluidos el
+// cupo y la lista de espera; newRepo devuelve repositorios vacíos.
+func RepositorySuite(t *testing.T, newRepo func(t *testing.T) repos) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		r := newRepo(t)
+		u := createUser(t, r.users, 1)
+		c := createCourse(t, r.courses, 0)
+
+		e := enroll(t, r.enrollments, u.ID, c.ID)
+		if e.ID == "" || e.Status != domain.EnrollmentPending || e.WaitlistPosition != nil {
+			t.Fatalf("created enrollment %+v, want a pending one with an ID", e)
+		}
+
+		got, err := r.enrollments.Get(ctx, e.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.UserID != u.ID || got.CourseID != c.ID || got.Status != domain.EnrollmentPending {
+			t.Errorf("Get = %+v", got)
+		}
+		if got.User == nil || got.User.Email != u.Email || got.Course == nil || got.Course.Name != c.Name {
+			t.Errorf("Get did not load the user and course: %+v, %+v", got.User, got.Course)
+		}
+		if active, err := r.enrollments.GetActive(ctx, u.ID, c.ID); err != nil || active.ID != e.ID {
+			t.Errorf("GetActive = %v, %v; want %s", active, err, e.ID)
+		}
+
+		if _, err := r.enrollments.Get(ctx, "missing"); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("Get(missing) error = %v, want ErrNotFound", err)
+		}
+		other := createCourse(t, r.courses, 0)
+		if _, err := r.enrollments.GetActive(ctx, u.ID, other.ID); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("GetActive(other course) error = %v, want ErrNotFound", err)
+		}
+		missing := &domain.Enrollment{UserID: u.ID, CourseID: "missing", Status: domain.EnrollmentPending}
+		if err := r.enrollments.Create(ctx, missing); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("Create in a missing course error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("one active enrollment per user and course", func(t *testing.T) {
+		r := newRepo(t)
+		u := createUser(t, r.users, 1)
+		c := createCourse(t, r.courses, 0)
+		first := enroll(t, r.enrollments, u.ID, c.ID)
+
+		again := &domain.Enrollment{UserID: u.ID, CourseID: c.ID, Status: domain.EnrollmentPending}
+		if err := r.enrollments.Create(ctx, again); apperr.From(err).Kind != apperr.KindConflict {
+			t.Fatalf("second Create error = %v, want a conflict", err)
+		}
+
+		if err := r.enrollments.UpdateStatus(ctx, first.ID, domain.EnrollmentPending, domain.EnrollmentWithdrawn); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.enrollments.GetActive(ctx, u.ID, c.ID); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("GetActive after withdrawing error = %v, want ErrNotFound", err)
+		}
+		enroll(t, r.enrollments, u.ID, c.ID)
+	})
+
+	t.Run("update status only from the expected status", func(t *testing.T) {
+		r := newRepo(t)
+		e := enroll(t, r.enrollments, createUser(t, r.users, 1).ID, createCourse(t, r.courses, 0).ID)
+
+		if err := r.enrollments.UpdateStatus(ctx, e.ID, domain.EnrollmentActive, domain.EnrollmentStudying); !errors.Is(err, enrollment.ErrStatusChanged) {
+			t.Errorf("UpdateStatus from a stale status error = %v, want ErrStatusChanged", err)
+		}
+		if err := r.enrollments.UpdateStatus(ctx, e.ID, domain.EnrollmentPending, domain.EnrollmentActive); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := r.enrollments.Get(ctx, e.ID); got.Status != domain.EnrollmentActive {
+			t.Errorf("status = %s, want active", got.Status)
+		}
+		if err := r.enrollments.UpdateStatus(ctx, "missing", domain.EnrollmentPending, domain.EnrollmentActive); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("UpdateStatus(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("waitlist", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			promoted bool
+			// free libera el lugar de seated y devuelve la lista de espera
+			// que debe quedar, como índices de waiting.
+			free func(t *testing.T, r repos, c *domain.Course, seated *domain.Enrollment, waiting []*domain.Enrollment) []int
+		}{
+			{"withdrawing promotes the first in line", true, func(t *testing.T, r repos, c *domain.Course, seated *domain.Enrollment, waiting []*domain.Enrollment) []int {
+				if err := r.enrollments.UpdateStatus(ctx, seated.ID, domain.EnrollmentPending, domain.EnrollmentWithdrawn); err != nil {
+					t.Fatal(err)
+				}
+				return []int{1, 2}
+			}},
+			{"deleting promotes the first in line", true, func(t *testing.T, r repos, c *domain.Course, seated *domain.Enrollment, waiting []*domain.Enrollment) []int {
+				if err := r.enrollments.Delete(ctx, seated.ID); err != nil {
+					t.Fatal(err)
+				}
+				return []int{1, 2}
+			}},
+			{"raising the capacity promotes in order", true, func(t *testing.T, r repos, c *domain.Course, seated *domain.Enrollment, waiting []*domain.Enrollment) []int {
+				capacity := 3
+				if err := r.courses.Update(ctx, c.ID, nil, nil, nil, &capacity, nil, nil); err != nil {
+					t.Fatal(err)
+				}
+				if err := r.enrollments.Promote(ctx, c.ID); err != nil {
+					t.Fatal(err)
+				}
+				return []int{2}
+			}},
+			{"leaving the waitlist closes the gap", false, func(t *testing.T, r repos, c *domain.Course, seated *domain.Enrollment, waiting []*domain.Enrollment) []int {
+				if err := r.enrollments.Delete(ctx, waiting[0].ID); err != nil {
+					t.Fatal(err)
+				}
+				if err := r.enrollments.UpdateStatus(ctx, waiting[1].ID, domain.EnrollmentWaitlist, domain.EnrollmentRejected); err != nil {
+					t.Fatal(err)
+				}
+				return []int{2}
+			}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				r := newRepo(t)
+				c := createCourse(t, r.courses, 1)
+				seated := enroll(t, r.enrollments, createUser(t, r.users, 0).ID, c.ID)
+				var waiting []*domain.Enrollment
+				for i := 1; i <= 3; i++ {
+					e := enroll(t, r.enrollments, createUser(t, r.users, i).ID, c.ID)
+					if e.Status != domain.EnrollmentWaitlist || e.WaitlistPosition == nil || *e.WaitlistPosition != i {
+						t.Fatalf("enrollment %d = %s at %v, want waitlisted at %d", i, e.Status, e.WaitlistPosition, i)
+					}
+					waiting = append(waiting, e)
+				}
+
+				want := tt.free(t, r, c, seated, waiting)
+
+				waitlist, err := r.enrollments.GetWaitlist(ctx, c.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(waitlist) != len(want) {
+					t.Fatalf("waitlist has %d entries, want %d", len(waitlist), len(want))
+				}
+				for i, e := range waitlist {
+					if e.ID != waiting[want[i]].ID || e.WaitlistPosition == nil || *e.WaitlistPosition != i+1 {
+						t.Errorf("waitlist[%d] = %s at %v, want %s at %d", i, e.ID, e.WaitlistPosition, waiting[want[i]].ID, i+1)
+					}
+				}
+				if tt.promoted {
+					if first, _ := r.enrollments.Get(ctx, waiting[0].ID); first.Status != domain.EnrollmentPending {
+						t.Errorf("first in line is %s, want pending", first.Status)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("new enrollments do not jump the waitlist", func(t *testing.T) {
+		r := newRepo(t)
+		c := createCourse(t, r.courses, 1)
+		enroll(t, r.enrollments, createUser(t, r.users, 0).ID, c.ID)
+		waiting := enroll(t, r.enrollments, createUser(t, r.users, 1).ID, c.ID)
+
+		// La capacidad cambia sin promover a nadie, como si Promote no se
+		// hubiera llamado todavía.
+		capacity := 2
+		if err := r.courses.Update(ctx, c.ID, nil, nil, nil, &capacity, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+		late := enroll(t, r.enrollments, createUser(t, r.users, 2).ID, c.ID)
+
+		if got, _ := r.enrollments.Get(ctx, waiting.ID); got.Status != domain.EnrollmentPending {
+			t.Errorf("waiting enrollment is %s, want pending", got.Status)
+		}
+		if late.Status != domain.EnrollmentWaitlist || late.WaitlistPosition == nil || *late.WaitlistPosition != 1 {
+			t.Errorf("late enrollment is %s at %v, want waitlisted at 1", late.Status, late.WaitlistPosition)
+		}
+	})
+
+	t.Run("list newest first with filters and pagination", func(t *testing.T) {
+		r := newRepo(t)
+		c1 := createCourse(t, r.courses, 0)
+		c2 := createCourse(t, r.courses, 0)
+		u1 := createUser(t, r.users, 1)
+		u2 := createUser(t, r.users, 2)
+		var ids []string
+		for _, pair := range [][2]string{{u1.ID, c1.ID}, {u1.ID, c2.ID}, {u2.ID, c1.ID}} {
+			ids = append(ids, enroll(t, r.enrollments, pair[0], pair[1]).ID)
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err := r.enrollments.UpdateStatus(ctx, ids[0], domain.EnrollmentPending, domain.EnrollmentActive); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name          string
+			filters       enrollment.Filters
+			limit, offset int
+			want          []string
+		}{
+			{"first page", enrollment.Filters{}, 2, 0, []string{ids[2], ids[1]}},
+			{"last page", enrollment.Filters{}, 2, 2, []string{ids[0]}},
+			{"zero limit", enrollment.Filters{}, 0, 0, nil},
+			{"negative limit", enrollment.Filters{}, -1, 0, []string{ids[2], ids[1], ids[0]}},
+			{"user", enrollment.Filters{UserID: u1.ID}, 10, 0, []string{ids[1], ids[0]}},
+			{"course", enrollment.Filters{CourseID: c1.ID}, 10, 0, []string{ids[2], ids[0]}},
+			{"status", enrollment.Filters{Status: domain.EnrollmentActive}, 10, 0, []string{ids[0]}},
+			{"user and course", enrollment.Filters{UserID: u2.ID, CourseID: c2.ID}, 10, 0, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				enrollments, err := r.enrollments.GetAll(ctx, tt.filters, tt.limit, tt.offset)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := enrollmentIDs(enrollments); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("GetAll = %v, want %v", got, tt.want)
+				}
+			})
+		}
+
+		count, err := r.enrollments.Count(ctx, enrollment.Filters{CourseID: c1.ID})
+		if err != nil || count != 2 {
+			t.Errorf("Count = %d, %v; want 2", count, err)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		r := newRepo(t)
+		e := enroll(t, r.enrollments, createUser(t, r.users, 1).ID, createCourse(t, r.courses, 0).ID)
+		if err := r.enrollments.Delete(ctx, e.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.enrollments.Get(ctx, e.ID); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("Get after Delete error = %v, want ErrNotFound", err)
+		}
+		if err := r.enrollments.Delete(ctx, e.ID); !errors.Is(err, enrollment.ErrNotFound) {
+			t.Errorf("second Delete error = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func createUser(t *testing.T, repo user.Repository, n int) *domain.User {
+	t.Helper()
+	u := &domain.User{FirstName: "Ana", LastName: "López", Email: fmt.Sprintf("u%d@example.com", n), Phone: fmt.Sprint(n)}
+	if err := repo.Create(context.Background(), u); err != nil {
+		t.Fatalf("create user %d: %v", n, err)
+	}
+	return u
+}
+
+func createCourse(t *testing.T, repo course.Repository, capacity int) *domain.Course {
+	t.Helper()
+	start := time.Date(2030, 2, 1, 0, 0, 0, 0, time.UTC)
+	c := &domain.Course{Name: "Go", StartDate: start, EndDate: start.AddDate(0, 4, 0), Capacity: capacity}
+	if err := repo.Create(context.Background(), c); err != nil {
+		t.Fatalf("create course: %v", err)
+	}
+	return c
+}
+
+func enroll(t *testing.T, repo enrollment.Repository, userID, courseID string) *domain.Enrollment {
+	t.Helper()
+	e := &domain.Enrollment{UserID: userID, CourseID: courseID, Status: domain.EnrollmentPending}
+	if err := repo.Create(context.Background(), e); err != nil {
+		t.Fatalf("enroll %s in %s: %v", userID, courseID, err)
+	}
+	return e
+}
+
+func enrollmentIDs(enrollments []domain.Enrollment) []string {
+	var ids []string
+	for _, e := range enrollments {
+		ids = append(ids, e.ID)
+	}
+	return ids
+}
@@ -1,26 +1,62 @@
 package enrollment
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"github.com/raminpz/gocourse_web/internal/course"
 	"github.com/raminpz/gocourse_web/internal/domain"
 	"github.com/raminpz/gocourse_web/internal/user"
-	"log"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/metrics"
+	"github.com/raminpz/gocourse_web/pkg/tracing"
+	"log/slog"
+	"time"
 )
 
 type (
+	Filters struct {
+		UserID   string
+		CourseID string
+		Status   domain.EnrollmentStatus
+	}
 	Service interface {
-		Create(userID, courseID string) (*domain.Enrollment, error)
+		Create(ctx context.Context, userID, courseID string) (*domain.Enrollment, error)
+		GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Enrollment, error)
+		Get(ctx context.Context, id string) (*domain.Enrollment, error)
+		Update(ctx context.Context, id string, status *string) error
+		Transition(ctx context.Context, id, status string) (*domain.Enrollment, error)
+		Waitlist(ctx context.Context, courseID string) ([]domain.Enrollment, error)
+		Delete(ctx context.Context, id string) error
+		Count(ctx context.Context, filters Filters) (int, error)
+		Instructs(ctx context.Context, userID, courseID string) (bool, error)
 	}
 	service struct {
-		log       *log.Logger
+		log       *slog.Logger
 		userSrv   user.Service
 		courseSrv course.Service
 		repo      Repository
 	}
 )
 
-func NewService(repo Repository, logger *log.Logger, userSrv user.Service, courseSrv course.Service) Service {
+var tracer = tracing.Tracer("internal/enrollment")
+
+var (
+	ErrNotFound          = apperr.NotFound("enrollment_not_found", "enrollment does not exist")
+	ErrInvalidStatus     = apperr.Validation("invalid_status", "invalid enrollment status")
+	ErrEnrollmentNotOpen = apperr.Conflict("enrollment_not_open", "enrollment for this course is not open yet")
+	ErrEnrollmentClosed  = apperr.Conflict("enrollment_closed", "enrollment for this course is closed")
+	ErrCourseFinished    = apperr.Conflict("course_finished", "course has already finished")
+
+	// ErrInvalidTransition indica que el estado actual no permite pasar al solicitado.
+	ErrInvalidTransition = apperr.Conflict("invalid_transition", "enrollment status transition is not allowed")
+	// ErrMissingPrerequisites indica que el usuario aún no completó algún prerrequisito.
+	ErrMissingPrerequisites = apperr.Conflict("missing_prerequisites", "user has not completed the course prerequisites")
+	// ErrAlreadyEnrolled indica que el usuario ya tiene una inscripción vigente en el curso.
+	ErrAlreadyEnrolled = apperr.Conflict("already_enrolled", "user is already enrolled in this course")
+)
+
+func NewService(repo Repository, logger *slog.Logger, userSrv user.Service, courseSrv course.Service) Service {
 	return &service{
 		log:       logger,
 		userSrv:   userSrv,
@@ -29,24 +65,189 @@ func NewService(repo Repository, logger *log.Logger, userSrv user.Service, cours
 	}
 }
 
-func (s service) Create(userID, courseID string) (*domain.Enrollment, error) {
+func (s service) Create(ctx context.Context, userID, courseID string) (*domain.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.Create")
+	defer span.End()
+
 	enroll := &domain.Enrollment{
 		UserID:   userID,
 		CourseID: courseID,
-		Status:   "P",
+		Status:   domain.EnrollmentPending,
+	}
+	if _, err := s.userSrv.Get(ctx, enroll.UserID); err != nil {
+		return nil, err
+	}
+	course, err := s.courseSrv.Get(ctx, enroll.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEnrollmentWindow(course, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.checkPrerequisites(ctx, userID, course); err != nil {
+		return nil, err
 	}
-	if _, err := s.userSrv.Get(enroll.UserID); err != nil {
-		return nil, errors.New("user id does not exist: " + enroll.UserID)
+	existing, err := s.repo.GetActive(ctx, userID, courseID)
+	if err == nil {
+		return nil, alreadyEnrolled(existing.ID)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, enroll); err != nil {
+		// Otra petición pudo inscribir al usuario entre la verificación y el insert.
+		if existing, getErr := s.repo.GetActive(ctx, userID, courseID); getErr == nil {
+			return nil, alreadyEnrolled(existing.ID)
+		}
+		s.log.ErrorContext(ctx, "error creating enrollment", "error", err)
+		return nil, err
+	}
+
+	s.log.InfoContext(ctx, "enrollment created", "enrollment_id", enroll.ID, "status", enroll.Status)
+	metrics.EnrollmentsCreated.WithLabelValues(enroll.CourseID, enroll.Status.String()).Inc()
+	return enroll, nil
+}
+
+func (s service) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.GetAll")
+	defer span.End()
+
+	enrollments, err := s.repo.GetAll(ctx, filters, limit, offset)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error getting enrollments", "error", err)
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+func (s service) Get(ctx context.Context, id string) (*domain.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.Get")
+	defer span.End()
+
+	enroll, err := s.repo.Get(ctx, id)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error getting enrollment", "enrollment_id", id, "error", err)
+		return nil, err
+	}
+	return enroll, nil
+}
+
+func (s service) Update(ctx context.Context, id string, status *string) error {
+	ctx, span := tracer.Start(ctx, "enrollment.Update")
+	defer span.End()
+
+	if status == nil {
+		return nil
 	}
-	if _, err := s.courseSrv.Get(enroll.CourseID); err != nil {
-		return nil, errors.New("course id does not exist: " + enroll.CourseID)
+	_, err := s.Transition(ctx, id, *status)
+	return err
+}
+
+func (s service) Transition(ctx context.Context, id, status string) (*domain.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.Transition")
+	defer span.End()
+
+	next, ok := domain.ParseEnrollmentStatus(status)
+	if !ok {
+		return nil, ErrInvalidStatus
 	}
 
-	if err := s.repo.Create(enroll); err != nil {
-		s.log.Println("error creating enrollment:", err)
+	enroll, err := s.repo.Get(ctx, id)
+	if err != nil {
 		return nil, err
 	}
+	if !enroll.Status.CanTransitionTo(next) {
+		return nil, ErrInvalidTransition.
+			WithMessage(fmt.Sprintf("cannot change enrollment status from %s to %s", enroll.Status, next)).
+			WithDetails(map[string]string{"from": enroll.Status.String(), "to": next.String()})
+	}
 
-	s.log.Println("enrollment created with id:", enroll.ID)
+	if err := s.repo.UpdateStatus(ctx, id, enroll.Status, next); err != nil {
+		s.log.ErrorContext(ctx, "error updating enrollment status", "enrollment_id", id, "error", err)
+		return nil, err
+	}
+	s.log.InfoContext(ctx, "enrollment status changed", "enrollment_id", id, "from", enroll.Status, "to", next)
+	enroll.Status = next
+	enroll.WaitlistPosition = nil
 	return enroll, nil
 }
+
+func (s service) Waitlist(ctx context.Context, courseID string) ([]domain.Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.Waitlist")
+	defer span.End()
+
+	if _, err := s.courseSrv.Get(ctx, courseID); err != nil {
+		return nil, err
+	}
+	enrollments, err := s.repo.GetWaitlist(ctx, courseID)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error getting waitlist", "course_id", courseID, "error", err)
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+func (s service) Delete(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "enrollment.Delete")
+	defer span.End()
+
+	return s.repo.Delete(ctx, id)
+}
+
+func (s service) Count(ctx context.Context, filters Filters) (int, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.Count")
+	defer span.End()
+
+	count, err := s.repo.Count(ctx, filters)
+	if err != nil {
+		s.log.ErrorContext(ctx, "error counting enrollments", "error", err)
+		return 0, err
+	}
+	return count, nil
+}
+
+func (s service) Instructs(ctx context.Context, userID, courseID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "enrollment.Instructs")
+	defer span.End()
+
+	return s.courseSrv.IsInstructor(ctx, courseID, userID)
+}
+
+func checkEnrollmentWindow(course *domain.Course, now time.Time) error {
+	if !now.Before(course.EndDate) {
+		return ErrCourseFinished
+	}
+	if course.EnrollmentOpen != nil && now.Before(*course.EnrollmentOpen) {
+		return ErrEnrollmentNotOpen
+	}
+	if !now.Before(course.EnrollmentCloseAt()) {
+		return ErrEnrollmentClosed
+	}
+	return nil
+}
+
+func (s service) checkPrerequisites(ctx context.Context, userID string, course *domain.Course) error {
+	var missing []string
+	for _, prerequisite := range course.Prerequisites {
+		completed, err := s.repo.Count(ctx, Filters{
+			UserID:   userID,
+			CourseID: prerequisite.ID,
+			Status:   domain.EnrollmentCompleted,
+		})
+		if err != nil {
+			return err
+		}
+		if completed == 0 {
+			missing = append(missing, prerequisite.ID)
+		}
+	}
+	if len(missing) > 0 {
+		return ErrMissingPrerequisites.WithDetails(map[string][]string{"missing_course_ids": missing})
+	}
+	return nil
+}
+
+func alreadyEnrolled(enrollmentID string) error {
+	return ErrAlreadyEnrolled.WithDetails(map[string]string{"enrollment_id": enrollmentID})
+}
@@ -0,0 +1,248 @@
+package enrollment_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// hookedRepo es un repositorio en memoria que llama a before antes de cada
+// Create; si before devuelve un error, Create falla con él sin guardar nada.
+type hookedRepo struct {
+	enrollment.Repository
+	before func(ctx context.Context, enroll *domain.Enrollment) error
+}
+
+func (r hookedRepo) Create(ctx context.Context, enroll *domain.Enrollment) error {
+	if err := r.before(ctx, enroll); err != nil {
+		return err
+	}
+	return r.Repository.Create(ctx, enroll)
+}
+
+// newRepos arma repositorios en memoria con los usuarios ana y bea, el
+// instructor teacher y los cursos:
+//
+//   - go, dictado por teacher, y rust;
+//   - soon, que abre la inscripción en 10 días, started y late, que ya
+//     empezaron (late acepta inscripciones 5 días más) y finished;
+//   - advanced, que requiere go y rust.
+//
+// ana tiene e1, pendiente en go, y e2, retirada de rust.
+func newRepos(t *testing.T) repos {
+	t.Helper()
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	users := user.NewMemoryRepo(logger)
+	courses := course.NewMemoryRepo(logger, users)
+	r := repos{enrollment.NewMemoryRepo(logger, users, courses), users, courses}
+
+	for i, id := range []string{"ana", "bea", "teacher"} {
+		if err := users.Create(ctx, &domain.User{ID: id, Email: id + "@example.com", Phone: fmt.Sprint(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Now()
+	at := func(days int) time.Time { return now.AddDate(0, 0, days) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	for _, c := range []domain.Course{
+		{ID: "go", StartDate: at(30), EndDate: at(90)},
+		{ID: "rust", StartDate: at(30), EndDate: at(90)},
+		{ID: "soon", StartDate: at(30), EndDate: at(90), EnrollmentOpen: ptr(at(10))},
+		{ID: "started", StartDate: at(-1), EndDate: at(30)},
+		{ID: "late", StartDate: at(-10), EndDate: at(30), EnrollmentClose: ptr(at(5))},
+		{ID: "finished", StartDate: at(-90), EndDate: at(-1)},
+		{ID: "advanced", StartDate: at(30), EndDate: at(90)},
+	} {
+		if err := courses.Create(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, prerequisiteID := range []string{"go", "rust"} {
+		if err := courses.AddPrerequisite(ctx, "advanced", prerequisiteID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := courses.AddInstructor(ctx, "go", "teacher"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []domain.Enrollment{
+		{ID: "e1", UserID: "ana", CourseID: "go", Status: domain.EnrollmentPending},
+		{ID: "e2", UserID: "ana", CourseID: "rust", Status: domain.EnrollmentWithdrawn},
+	} {
+		if err := r.enrollments.Create(ctx, &e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func newService(r repos) enrollment.Service {
+	logger := slog.New(slog.DiscardHandler)
+	users := user.NewService(logger, r.users)
+	courses := course.NewService(r.courses, logger, users, r.enrollments.Promote)
+	return enrollment.NewService(r.enrollments, logger, users, courses)
+}
+
+// complete marca como completado el curso courseID para userID.
+func complete(t *testing.T, r repos, userID, courseID string) {
+	t.Helper()
+	e := &domain.Enrollment{UserID: userID, CourseID: courseID, Status: domain.EnrollmentCompleted}
+	if err := r.enrollments.Create(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServiceCreate(t *testing.T) {
+	errInsert := errors.New("insert failed")
+	tests := []struct {
+		name     string
+		userID   string
+		courseID string
+		setup    func(t *testing.T, r *repos)
+		wantErr  error
+		// wantStored es cuántas inscripciones nuevas de userID en courseID
+		// quedan después de un error.
+		wantStored int
+	}{
+		{name: "enrolls", userID: "ana", courseID: "late"},
+		{name: "after withdrawing", userID: "ana", courseID: "rust"},
+		{name: "missing user", userID: "nobody", courseID: "late", wantErr: user.ErrNotFound},
+		{name: "missing course", userID: "ana", courseID: "missing", wantErr: course.ErrNotFound},
+		{name: "before enrollment opens", userID: "ana", courseID: "soon", wantErr: enrollment.ErrEnrollmentNotOpen},
+		{name: "after the course starts", userID: "ana", courseID: "started", wantErr: enrollment.ErrEnrollmentClosed},
+		{name: "finished course", userID: "ana", courseID: "finished", wantErr: enrollment.ErrCourseFinished},
+		{name: "missing prerequisites", userID: "bea", courseID: "advanced", wantErr: enrollment.ErrMissingPrerequisites,
+			setup: func(t *testing.T, r *repos) { complete(t, *r, "bea", "go") }},
+		{name: "completed prerequisites", userID: "bea", courseID: "advanced",
+			setup: func(t *testing.T, r *repos) { complete(t, *r, "bea", "go"); complete(t, *r, "bea", "rust") }},
+		{name: "already enrolled", userID: "ana", courseID: "go", wantErr: enrollment.ErrAlreadyEnrolled},
+		{name: "enrolled by a concurrent request", userID: "ana", courseID: "late", wantErr: enrollment.ErrAlreadyEnrolled, wantStored: 1,
+			setup: func(t *testing.T, r *repos) {
+				inner := r.enrollments
+				r.enrollments = hookedRepo{inner, func(ctx context.Context, e *domain.Enrollment) error {
+					return inner.Create(ctx, &domain.Enrollment{UserID: e.UserID, CourseID: e.CourseID, Status: domain.EnrollmentPending})
+				}}
+			}},
+		{name: "insert fails", userID: "ana", courseID: "late", wantErr: errInsert,
+			setup: func(t *testing.T, r *repos) {
+				r.enrollments = hookedRepo{r.enrollments, func(context.Context, *domain.Enrollment) error { return errInsert }}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := newRepos(t)
+			if tt.setup != nil {
+				tt.setup(t, &r)
+			}
+			// Solo cuentan las inscripciones vigentes: las completadas de los
+			// prerrequisitos y las retiradas no.
+			filters := enrollment.Filters{UserID: tt.userID, CourseID: tt.courseID, Status: domain.EnrollmentPending}
+			before, _ := r.enrollments.Count(ctx, filters)
+
+			enroll, err := newService(r).Create(ctx, tt.userID, tt.courseID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if after, _ := r.enrollments.Count(ctx, filters); after-before != tt.wantStored {
+					t.Errorf("Create stored %d enrollments after failing, want %d", after-before, tt.wantStored)
+				}
+				return
+			}
+			got, err := r.enrollments.Get(ctx, enroll.ID)
+			if err != nil || got.UserID != tt.userID || got.CourseID != tt.courseID || got.Status != domain.EnrollmentPending {
+				t.Errorf("stored %+v, %v; want the returned enrollment %+v", got, err, enroll)
+			}
+		})
+	}
+}
+
+func TestServiceTransition(t *testing.T) {
+	tests := []struct {
+		name, id, status string
+		want             domain.EnrollmentStatus
+		wantErr          error
+	}{
+		{name: "by name", id: "e1", status: "active", want: domain.EnrollmentActive},
+		{name: "by code", id: "e1", status: "R", want: domain.EnrollmentRejected},
+		{name: "not allowed", id: "e1", status: "completed", want: domain.EnrollmentPending, wantErr: enrollment.ErrInvalidTransition},
+		{name: "from a final status", id: "e2", status: "pending", want: domain.EnrollmentWithdrawn, wantErr: enrollment.ErrInvalidTransition},
+		{name: "unknown status", id: "e1", status: "done", want: domain.EnrollmentPending, wantErr: enrollment.ErrInvalidStatus},
+		{name: "missing enrollment", id: "missing", status: "active", wantErr: enrollment.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			r := newRepos(t)
+			enroll, err := newService(r).Transition(ctx, tt.id, tt.status)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Transition error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && enroll.Status != tt.want {
+				t.Errorf("returned status = %s, want %s", enroll.Status, tt.want)
+			}
+			if got, err := r.enrollments.Get(ctx, tt.id); err == nil && got.Status != tt.want {
+				t.Errorf("stored status = %s, want %s", got.Status, tt.want)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateWithoutStatus(t *testing.T) {
+	if err := newService(newRepos(t)).Update(context.Background(), "missing", nil); err != nil {
+		t.Fatalf("Update without status error = %v, want nil", err)
+	}
+}
+
+func TestServiceWaitlist(t *testing.T) {
+	ctx := context.Background()
+	r := newRepos(t)
+	srv := newService(r)
+	if _, err := srv.Waitlist(ctx, "missing"); !errors.Is(err, course.ErrNotFound) {
+		t.Errorf("Waitlist(missing) error = %v, want course.ErrNotFound", err)
+	}
+
+	// Con un solo lugar, que ocupa e1, bea queda en espera.
+	capacity := 1
+	if err := r.courses.Update(ctx, "go", nil, nil, nil, &capacity, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	waiting, err := srv.Create(ctx, "bea", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := srv.Waitlist(ctx, "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := enrollmentIDs(got); fmt.Sprint(ids) != fmt.Sprintf("[%s]", waiting.ID) {
+		t.Errorf("Waitlist(go) = %v, want [%s]", ids, waiting.ID)
+	}
+}
+
+func TestServiceInstructs(t *testing.T) {
+	srv := newService(newRepos(t))
+	for _, tt := range []struct {
+		userID, courseID string
+		want             bool
+	}{
+		{"teacher", "go", true},
+		{"teacher", "late", false},
+		{"ana", "go", false},
+	} {
+		if got, err := srv.Instructs(context.Background(), tt.userID, tt.courseID); err != nil || got != tt.want {
+			t.Errorf("Instructs(%s, %s) = %v, %v; want %v", tt.userID, tt.courseID, got, err, tt.want)
+		}
+	}
+}
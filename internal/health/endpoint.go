@@ -0,0 +1,44 @@
+package health
+
+import (
+	"github.com/raminpz/gocourse_web/pkg/response"
+	"net/http"
+)
+
+type (
+	Controller func(w http.ResponseWriter, r *http.Request)
+
+	Endpoint struct {
+		Live  Controller
+		Ready Controller
+	}
+)
+
+func MakeEndpoints(s Service) Endpoint {
+	return Endpoint{
+		Live:  makeLiveEndpoint(s),
+		Ready: makeReadyEndpoint(s),
+	}
+}
+
+func makeLiveEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, s.Live())
+	}
+}
+
+func makeReadyEndpoint(s Service) Controller {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, s.Ready(r.Context()))
+	}
+}
+
+// respond responde 200 si report está up y 503 si no, que es lo que miran los
+// orquestadores para decidir si mandan tráfico o reinician el proceso.
+func respond(w http.ResponseWriter, r *http.Request, report Report) {
+	status := http.StatusOK
+	if report.Status != StatusUp {
+		status = http.StatusServiceUnavailable
+	}
+	response.JSON(w, r, &response.Response{Status: status, Data: report})
+}
@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"github.com/raminpz/gocourse_web/pkg/migrate"
+	"gorm.io/gorm"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// checkTimeout es el tiempo máximo de cada chequeo de readiness.
+const checkTimeout = 2 * time.Second
+
+type (
+	Check struct {
+		Status    string      `json:"status"`
+		LatencyMs float64     `json:"latency_ms"`
+		Error     string      `json:"error,omitempty"`
+		Detail    interface{} `json:"detail,omitempty"`
+	}
+
+	Report struct {
+		Status       string           `json:"status"`
+		ShuttingDown bool             `json:"shutting_down,omitempty"`
+		Checks       map[string]Check `json:"checks,omitempty"`
+	}
+
+	MigrationDetail struct {
+		Applied int64 `json:"applied"`
+		Latest  int64 `json:"latest"`
+	}
+
+	Service interface {
+		Live() Report
+		Ready(ctx context.Context) Report
+		Shutdown()
+	}
+
+	service struct {
+		db           *gorm.DB
+		migrator     *migrate.Migrator
+		shuttingDown *atomic.Bool
+	}
+)
+
+// NewService revisa db y migrator en Ready; con db nil (STORAGE=memory) no hay
+// dependencias que revisar.
+func NewService(db *gorm.DB, migrator *migrate.Migrator) Service {
+	return &service{
+		db:           db,
+		migrator:     migrator,
+		shuttingDown: &atomic.Bool{},
+	}
+}
+
+// Live indica que el proceso responde; no revisa dependencias.
+func (s service) Live() Report {
+	return Report{Status: StatusUp}
+}
+
+// Ready revisa la base de datos y que no haya migraciones pendientes. Deja de
+// estar listo en cuanto empieza el apagado para que no lleguen más peticiones.
+func (s service) Ready(ctx context.Context) Report {
+	if s.shuttingDown.Load() {
+		return Report{Status: StatusDown, ShuttingDown: true}
+	}
+
+	report := Report{Status: StatusUp}
+	if s.db == nil {
+		return report
+	}
+	report.Checks = map[string]Check{
+		"database":   s.check(ctx, s.pingDB),
+		"migrations": s.check(ctx, s.checkMigrations),
+	}
+	for _, c := range report.Checks {
+		if c.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// Shutdown marca el servicio como apagándose.
+func (s service) Shutdown() {
+	s.shuttingDown.Store(true)
+}
+
+func (s service) check(ctx context.Context, fn func(ctx context.Context) (interface{}, error)) Check {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := fn(ctx)
+	c := Check{
+		Status:    StatusUp,
+		LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+	if err != nil {
+		c.Status = StatusDown
+		c.Error = err.Error()
+	}
+	return c
+}
+
+func (s service) pingDB(ctx context.Context) (interface{}, error) {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return nil, sqlDB.PingContext(ctx)
+}
+
+func (s service) checkMigrations(ctx context.Context) (interface{}, error) {
+	applied, latest, err := s.migrator.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	detail := MigrationDetail{Applied: applied, Latest: latest}
+	if applied < latest {
+		return detail, fmt.Errorf("database is at version %d, latest is %d", applied, latest)
+	}
+	return detail, nil
+}
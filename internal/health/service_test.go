@@ -0,0 +1,167 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/raminpz/gocourse_web/internal/health"
+	"github.com/raminpz/gocourse_web/internal/testdb"
+	"github.com/raminpz/gocourse_web/migrations"
+	"github.com/raminpz/gocourse_web/pkg/migrate"
+	"gorm.io/gorm"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newMigrator(t *testing.T, db *gorm.DB) *migrate.Migrator {
+	t.Helper()
+	m, err := migrate.New(db, migrations.FS, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestServiceLive(t *testing.T) {
+	s := health.NewService(nil, nil)
+	s.Shutdown()
+	if got := s.Live(); got.Status != health.StatusUp {
+		t.Errorf("Live() = %+v, want up while shutting down", got)
+	}
+}
+
+func TestServiceReady(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		setup  func(t *testing.T) health.Service
+		status string
+		checks map[string]string
+	}{
+		{
+			name:   "memory storage",
+			setup:  func(t *testing.T) health.Service { return health.NewService(nil, nil) },
+			status: health.StatusUp,
+		},
+		{
+			name: "migrated database",
+			setup: func(t *testing.T) health.Service {
+				db := testdb.Open(t)
+				return health.NewService(db, newMigrator(t, db))
+			},
+			status: health.StatusUp,
+			checks: map[string]string{"database": health.StatusUp, "migrations": health.StatusUp},
+		},
+		{
+			name: "pending migration",
+			setup: func(t *testing.T) health.Service {
+				db := testdb.Open(t)
+				m := newMigrator(t, db)
+				if _, err := m.Down(1); err != nil {
+					t.Fatal(err)
+				}
+				return health.NewService(db, m)
+			},
+			status: health.StatusDown,
+			checks: map[string]string{"database": health.StatusUp, "migrations": health.StatusDown},
+		},
+		{
+			name: "closed database",
+			setup: func(t *testing.T) health.Service {
+				db := testdb.Open(t)
+				m := newMigrator(t, db)
+				sqlDB, err := db.DB()
+				if err != nil {
+					t.Fatal(err)
+				}
+				sqlDB.Close()
+				return health.NewService(db, m)
+			},
+			status: health.StatusDown,
+			checks: map[string]string{"database": health.StatusDown, "migrations": health.StatusDown},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := tt.setup(t).Ready(ctx)
+			if report.Status != tt.status || report.ShuttingDown {
+				t.Errorf("Ready() = %+v, want %s", report, tt.status)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("checks = %+v, want %v", report.Checks, tt.checks)
+			}
+			for name, status := range tt.checks {
+				c := report.Checks[name]
+				if c.Status != status || (status == health.StatusDown) != (c.Error != "") {
+					t.Errorf("check %s = %+v, want %s", name, c, status)
+				}
+			}
+		})
+	}
+}
+
+func TestServiceReadyMigrationDetail(t *testing.T) {
+	db := testdb.Open(t)
+	m := newMigrator(t, db)
+	if _, err := m.Down(1); err != nil {
+		t.Fatal(err)
+	}
+	_, latest, err := m.Version(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := health.NewService(db, m).Ready(context.Background()).Checks["migrations"]
+	want := health.MigrationDetail{Applied: latest - 1, Latest: latest}
+	if c.Detail != want {
+		t.Errorf("migrations detail = %+v, want %+v", c.Detail, want)
+	}
+}
+
+func TestServiceShutdown(t *testing.T) {
+	db := testdb.Open(t)
+	s := health.NewService(db, newMigrator(t, db))
+	s.Shutdown()
+
+	report := s.Ready(context.Background())
+	if report.Status != health.StatusDown || !report.ShuttingDown || report.Checks != nil {
+		t.Errorf("Ready() after Shutdown = %+v, want down without checks", report)
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	up := health.NewService(nil, nil)
+	down := health.NewService(nil, nil)
+	down.Shutdown()
+
+	tests := []struct {
+		name     string
+		endpoint func(health.Endpoint) health.Controller
+		s        health.Service
+		status   int
+		report   string
+	}{
+		{"live", func(e health.Endpoint) health.Controller { return e.Live }, up, http.StatusOK, health.StatusUp},
+		{"live while shutting down", func(e health.Endpoint) health.Controller { return e.Live }, down, http.StatusOK, health.StatusUp},
+		{"ready", func(e health.Endpoint) health.Controller { return e.Ready }, up, http.StatusOK, health.StatusUp},
+		{"ready while shutting down", func(e health.Endpoint) health.Controller { return e.Ready }, down, http.StatusServiceUnavailable, health.StatusDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.endpoint(health.MakeEndpoints(tt.s))(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var body struct {
+				Status int           `json:"status"`
+				Data   health.Report `json:"data"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.status || body.Status != tt.status || body.Data.Status != tt.report {
+				t.Errorf("response = %d %+v, want %d with %s", w.Code, body, tt.status, tt.report)
+			}
+		})
+	}
+}
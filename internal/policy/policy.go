@@ -0,0 +1,96 @@
+package policy
+
+import (
+	"context"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+)
+
+type (
+	// Actor es quien realiza la petición. Un Actor vacío representa a un
+	// visitante no autenticado.
+	Actor struct {
+		ID   string
+		Role domain.Role
+	}
+
+	Action string
+
+	// Resource describe lo que se quiere tocar, en relación con el Actor:
+	// OwnerID es el usuario dueño del recurso (el propio usuario, o el inscrito
+	// en una inscripción) e Instructs indica si el Actor dicta el curso involucrado.
+	Resource struct {
+		OwnerID   string
+		Instructs bool
+	}
+
+	contextKey struct{}
+)
+
+const (
+	ReadUser   Action = "user:read"
+	ListUsers  Action = "user:list"
+	UpdateUser Action = "user:update"
+	DeleteUser Action = "user:delete"
+	AssignRole Action = "user:assign_role"
+
+	ReadCourse   Action = "course:read"
+	CreateCourse Action = "course:create"
+	UpdateCourse Action = "course:update"
+	DeleteCourse Action = "course:delete"
+
+	CreateEnrollment Action = "enrollment:create"
+	ReadEnrollment   Action = "enrollment:read"
+	UpdateEnrollment Action = "enrollment:update"
+	DeleteEnrollment Action = "enrollment:delete"
+)
+
+var ErrForbidden = apperr.Forbidden("forbidden", "you are not allowed to perform this action")
+
+// Authorize decide si actor puede ejecutar action sobre res. No depende de
+// HTTP ni de la base de datos: quien llama arma el Resource con lo que sabe.
+func Authorize(actor Actor, action Action, res Resource) error {
+	if actor.ID == "" {
+		return ErrForbidden
+	}
+	if actor.Role == domain.RoleAdmin {
+		return nil
+	}
+
+	isOwner := res.OwnerID != "" && res.OwnerID == actor.ID
+	isInstructor := actor.Role == domain.RoleInstructor
+
+	allowed := false
+	switch action {
+	case ReadUser, UpdateUser:
+		allowed = isOwner
+	case ReadCourse:
+		allowed = true
+	case CreateCourse:
+		allowed = isInstructor
+	case UpdateCourse:
+		allowed = isInstructor && res.Instructs
+	case CreateEnrollment:
+		allowed = isOwner
+	case ReadEnrollment:
+		allowed = isOwner || (isInstructor && res.Instructs)
+	case UpdateEnrollment:
+		allowed = isInstructor && res.Instructs
+	}
+
+	if !allowed {
+		return ErrForbidden
+	}
+	return nil
+}
+
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// ActorFrom devuelve el Actor que dejó el middleware de autenticación, o
+// un Actor vacío si la petición no está autenticada.
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(contextKey{}).(Actor)
+	return actor
+}
@@ -0,0 +1,86 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/policy"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	var (
+		anonymous  = policy.Actor{}
+		admin      = policy.Actor{ID: "admin", Role: domain.RoleAdmin}
+		instructor = policy.Actor{ID: "teacher", Role: domain.RoleInstructor}
+		student    = policy.Actor{ID: "ana", Role: domain.RoleStudent}
+
+		own       = policy.Resource{OwnerID: "ana"}
+		other     = policy.Resource{OwnerID: "bea"}
+		taught    = policy.Resource{OwnerID: "bea", Instructs: true}
+		noOwner   = policy.Resource{}
+		ownTaught = policy.Resource{OwnerID: "teacher", Instructs: true}
+	)
+	tests := []struct {
+		name   string
+		actor  policy.Actor
+		action policy.Action
+		res    policy.Resource
+		allow  bool
+	}{
+		{"anonymous reads a course", anonymous, policy.ReadCourse, noOwner, false},
+		{"admin deletes a user", admin, policy.DeleteUser, other, true},
+		{"admin assigns a role", admin, policy.AssignRole, other, true},
+
+		{"student reads themselves", student, policy.ReadUser, own, true},
+		{"student reads another user", student, policy.ReadUser, other, false},
+		{"student updates themselves", student, policy.UpdateUser, own, true},
+		{"student updates another user", student, policy.UpdateUser, other, false},
+		{"student lists users", student, policy.ListUsers, noOwner, false},
+		{"student deletes themselves", student, policy.DeleteUser, own, false},
+		{"student assigns a role to themselves", student, policy.AssignRole, own, false},
+		{"student reads a user without owner", student, policy.ReadUser, noOwner, false},
+
+		{"student reads a course", student, policy.ReadCourse, noOwner, true},
+		{"student creates a course", student, policy.CreateCourse, noOwner, false},
+		{"instructor creates a course", instructor, policy.CreateCourse, noOwner, true},
+		{"instructor updates a course they teach", instructor, policy.UpdateCourse, ownTaught, true},
+		{"instructor updates another course", instructor, policy.UpdateCourse, noOwner, false},
+		{"student updates a course", student, policy.UpdateCourse, policy.Resource{Instructs: true}, false},
+		{"instructor deletes a course they teach", instructor, policy.DeleteCourse, ownTaught, false},
+
+		{"student enrolls themselves", student, policy.CreateEnrollment, own, true},
+		{"student enrolls another user", student, policy.CreateEnrollment, other, false},
+		{"student reads their enrollment", student, policy.ReadEnrollment, own, true},
+		{"student reads another enrollment", student, policy.ReadEnrollment, other, false},
+		{"instructor reads an enrollment in their course", instructor, policy.ReadEnrollment, taught, true},
+		{"instructor reads an enrollment in another course", instructor, policy.ReadEnrollment, other, false},
+		{"student updates their enrollment", student, policy.UpdateEnrollment, own, false},
+		{"instructor updates an enrollment in their course", instructor, policy.UpdateEnrollment, taught, true},
+		{"instructor updates an enrollment in another course", instructor, policy.UpdateEnrollment, other, false},
+		{"student deletes their enrollment", student, policy.DeleteEnrollment, own, false},
+		{"instructor deletes an enrollment in their course", instructor, policy.DeleteEnrollment, taught, false},
+		{"unknown action", student, policy.Action("course:archive"), own, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Authorize(tt.actor, tt.action, tt.res)
+			if tt.allow && err != nil {
+				t.Errorf("Authorize = %v, want allowed", err)
+			}
+			if !tt.allow && !errors.Is(err, policy.ErrForbidden) {
+				t.Errorf("Authorize = %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
+
+func TestActorFrom(t *testing.T) {
+	if actor := policy.ActorFrom(context.Background()); actor != (policy.Actor{}) {
+		t.Errorf("ActorFrom(empty context) = %+v, want an anonymous actor", actor)
+	}
+	want := policy.Actor{ID: "ana", Role: domain.RoleStudent}
+	if actor := policy.ActorFrom(policy.WithActor(context.Background(), want)); actor != want {
+		t.Errorf("ActorFrom = %+v, want %+v", actor, want)
+	}
+}
@@ -0,0 +1,35 @@
+// Package testdb abre bases SQLite en memoria con las migraciones aplicadas,
+// para probar los repositorios con base de datos sin un servidor.
+package testdb
+
+import (
+	"github.com/raminpz/gocourse_web/pkg/bootstrap"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// Open devuelve una base vacía y migrada que se cierra al terminar t. Cada
+// llamada tiene su propia base.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	db, err := bootstrap.DBConnection(config.Database{
+		Driver:       "sqlite",
+		Migrate:      true,
+		QueryTimeout: 5 * time.Second,
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	// Los "record not found" que gorm registra son parte de lo que se prueba.
+	db.Logger = logger.Discard
+	t.Cleanup(func() {
+		if err := bootstrap.CloseDB(db); err != nil {
+			t.Errorf("close sqlite: %v", err)
+		}
+	})
+	return db
+}
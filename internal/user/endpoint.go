@@ -3,7 +3,12 @@ package user
 import (
 	"encoding/json"
 	"github.com/gorilla/mux"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/policy"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
 	"github.com/raminpz/gocourse_web/pkg/meta"
+	"github.com/raminpz/gocourse_web/pkg/response"
+	"github.com/raminpz/gocourse_web/pkg/validate"
 	"net/http"
 	"strconv"
 )
@@ -19,26 +24,25 @@ type (
 	}
 
 	CreateReq struct {
-		FirstName string `json:"first_name"`
-		LastName  string `json:"last_name"`
-		Email     string `json:"email"`
-		Phone     string `json:"phone"`
-		Password  string `json:"password"`
+		FirstName string `json:"first_name" validate:"required,max=50"`
+		LastName  string `json:"last_name" validate:"required,max=50"`
+		Email     string `json:"email" validate:"required,max=50,email"`
+		Phone     string `json:"phone" validate:"required,max=11,digits"`
+		Password  string `json:"password" validate:"required,min=8,maxbytes=72"`
+		Role      string `json:"role" validate:"oneof=admin instructor student"`
+		Locale    string `json:"locale" validate:"oneof=es en"`
 	}
 
 	updateReq struct {
-		FirstName *string `json:"first_name"`
-		LastName  *string `json:"last_name"`
-		Email     *string `json:"email"`
-		Phone     *string `json:"phone"`
-		Password  *string `json:"password"`
-	}
-
-	Response struct {
-		Status int         `json:"status"`
-		Data   interface{} `json:"data,omitempty"`
-		Err    string      `json:"error,omitempty"`
-		Meta   *meta.Meta  `json:"meta,omitempty"`
+		FirstName *string `json:"first_name" validate:"required,max=50"`
+		LastName  *string `json:"last_name" validate:"required,max=50"`
+		Email     *string `json:"email" validate:"required,max=50,email"`
+		Phone     *string `json:"phone" validate:"required,max=11,digits"`
+		Password  *string `json:"password" validate:"required,min=8,maxbytes=72"`
+		Role      *string `json:"role" validate:"required,oneof=admin instructor student"`
+		Locale    *string `json:"locale" validate:"oneof=es en"`
+
+		CurrentPassword *string `json:"current_password"`
 	}
 )
 
@@ -56,79 +60,35 @@ func makeCreateEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req CreateReq
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "Invalid request format"})
-			return
-		}
-
-		if req.FirstName == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "first name is required"})
-			return
-		}
-		if len(req.FirstName) > 50 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "first name must be at most 50 characters"})
-			return
-		}
-
-		if req.LastName == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "last name is required"})
-			return
-		}
-		if len(req.LastName) > 50 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "last name must be at most 50 characters"})
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
 			return
 		}
 
-		if req.Email == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "email is required"})
-			return
-		}
-		if len(req.Email) > 50 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "email must be at most 50 characters"})
-			return
-		}
-
-		if req.Phone == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "phone is required"})
-			return
-		}
-		if len(req.Phone) > 11 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "phone must be at most 11 characters"})
+		if err := validate.Struct(req); err != nil {
+			response.Error(w, r, err)
 			return
 		}
 
-		if req.Password == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "password is required"})
-			return
-		}
-		if len(req.Password) < 8 {
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "password must be at least 8 characters"})
+		// Cualquiera puede registrarse como estudiante; elegir otro rol requiere ser admin.
+		if req.Role != "" && !authorize(w, r, policy.AssignRole, policy.Resource{}) {
 			return
 		}
 
-		user, err := s.Create(req.FirstName, req.LastName, req.Email, req.Phone)
+		user, err := s.Create(r.Context(), req.FirstName, req.LastName, req.Email, req.Phone, req.Password, domain.Role(req.Role), req.Locale)
 		if err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: err.Error()})
+			response.Error(w, r, err)
 			return
 		}
 
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: user})
+		response.JSON(w, r, response.OK(user))
 	}
 }
 
 func makeGetAllEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !authorize(w, r, policy.ListUsers, policy.Resource{}) {
+			return
+		}
 
 		v := r.URL.Query()
 
@@ -140,26 +100,23 @@ func makeGetAllEndpoint(s Service) Controller {
 		limit, _ := strconv.Atoi(v.Get("limit"))
 		page, _ := strconv.Atoi(v.Get("page"))
 
-		count, err := s.Count(filters)
+		count, err := s.Count(r.Context(), filters)
 		if err != nil {
-			w.WriteHeader(500)
-			json.NewEncoder(w).Encode(&Response{Status: 500, Err: err.Error()})
+			response.Error(w, r, err)
 			return
 		}
 		meta, err := meta.New(page, limit, count)
 		if err != nil {
-			w.WriteHeader(500)
-			json.NewEncoder(w).Encode(&Response{Status: 500, Err: err.Error()})
+			response.Error(w, r, err)
 			return
 		}
 
-		users, err := s.GetAll(filters, meta.Offset(), meta.Limit())
+		users, err := s.GetAll(r.Context(), filters, meta.Limit(), meta.Offset())
 		if err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: err.Error()})
+			response.Error(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: users, Meta: meta})
+		response.JSON(w, r, response.Page(users, meta))
 	}
 }
 
@@ -167,13 +124,16 @@ func makeGetEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := mux.Vars(r)
 		id := path["id"]
-		user, err := s.Get(id)
+		if !authorize(w, r, policy.ReadUser, policy.Resource{OwnerID: id}) {
+			return
+		}
+		user, err := s.Get(r.Context(), id)
 		if err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: err.Error()})
+			response.Error(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(user)
+		// V1 devolvía el usuario sin envelope.
+		response.Write(w, r, response.OK(user), func(*response.Response) interface{} { return user })
 	}
 }
 
@@ -181,31 +141,34 @@ func makeUpdateEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req updateReq
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "Invalid request format"})
-			return
-		}
-		if req.FirstName != nil && *req.FirstName == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "first name is required"})
+			response.Error(w, r, apperr.Validation("invalid_request", "Invalid request format"))
 			return
 		}
-
-		if req.LastName != nil && *req.LastName == "" {
-			w.WriteHeader(400)
-			json.NewEncoder(w).Encode(&Response{Status: 400, Err: "last name is required"})
+		if err := validate.Struct(req); err != nil {
+			response.Error(w, r, err)
 			return
 		}
 
 		path := mux.Vars(r)
 		id := path["id"]
+		if !authorize(w, r, policy.UpdateUser, policy.Resource{OwnerID: id}) {
+			return
+		}
+		var role *domain.Role
+		if req.Role != nil {
+			if !authorize(w, r, policy.AssignRole, policy.Resource{}) {
+				return
+			}
+			role = (*domain.Role)(req.Role)
+		}
 
-		if err := s.Update(id, req.FirstName, req.LastName, req.Email, req.Phone); err != nil {
-			w.WriteHeader(404)
-			json.NewEncoder(w).Encode(&Response{Status: 404, Err: "user does not exist"})
+		if err := s.Update(r.Context(), id, req.FirstName, req.LastName, req.Email, req.Phone, req.Locale, role, req.Password, req.CurrentPassword); err != nil {
+			response.Error(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(map[string]string{"data": "success"})
+		response.Write(w, r, response.OK("success"), func(*response.Response) interface{} {
+			return map[string]string{"data": "success"}
+		})
 	}
 }
 
@@ -213,12 +176,22 @@ func makeDeleteEndpoint(s Service) Controller {
 	return func(w http.ResponseWriter, r *http.Request) {
 		path := mux.Vars(r)
 		id := path["id"]
+		if !authorize(w, r, policy.DeleteUser, policy.Resource{OwnerID: id}) {
+			return
+		}
 
-		if err := s.Delete(id); err != nil {
-			w.WriteHeader(404)
-			json.NewEncoder(w).Encode(&Response{Status: 404, Err: "user does not exist"})
+		if err := s.Delete(r.Context(), id); err != nil {
+			response.Error(w, r, err)
 			return
 		}
-		json.NewEncoder(w).Encode(&Response{Status: 200, Data: "success"})
+		response.JSON(w, r, response.OK("success"))
+	}
+}
+
+func authorize(w http.ResponseWriter, r *http.Request, action policy.Action, res policy.Resource) bool {
+	if err := policy.Authorize(policy.ActorFrom(r.Context()), action, res); err != nil {
+		response.Error(w, r, err)
+		return false
 	}
+	return true
 }
@@ -0,0 +1,203 @@
+package user
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryRepo guarda los usuarios en memoria con la misma semántica que repo:
+// borrado lógico, email y teléfono únicos incluso entre los borrados y orden
+// por fecha de creación descendente.
+type memoryRepo struct {
+	log   *slog.Logger
+	mu    sync.RWMutex
+	users map[string]domain.User
+}
+
+func NewMemoryRepo(log *slog.Logger) Repository {
+	return &memoryRepo{
+		log:   log,
+		users: make(map[string]domain.User),
+	}
+}
+
+func (r *memoryRepo) Create(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	if user.Role == "" {
+		user.Role = domain.RoleStudent
+	}
+	err := r.checkUnique(user.ID, user.Email, user.Phone)
+	if _, ok := r.users[user.ID]; ok {
+		err = apperr.DB(gorm.ErrDuplicatedKey, ErrNotFound)
+	}
+	if err != nil {
+		r.log.ErrorContext(ctx, "error creating user", "error", err)
+		return err
+	}
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+
+	stored := *user
+	stored.Course = nil
+	r.users[user.ID] = stored
+	r.log.InfoContext(ctx, "user created", "user_id", user.ID)
+	return nil
+}
+
+func (r *memoryRepo) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := r.filter(filters)
+	sort.Slice(users, func(i, j int) bool {
+		// A igual fecha se ordena por ID para que las páginas sean estables.
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.After(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
+	})
+	// Igual que Limit y Offset de gorm: un límite negativo no limita y 0 no
+	// devuelve nada.
+	if offset > 0 {
+		if offset >= len(users) {
+			return []domain.User{}, nil
+		}
+		users = users[offset:]
+	}
+	if limit >= 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (r *memoryRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &user, nil
+}
+
+func (r *memoryRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryRepo) Update(ctx context.Context, id string, firstName *string, lastName *string, email *string, phone *string, locale *string, role *domain.Role, password *string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return ErrNotFound
+	}
+	if firstName != nil {
+		user.FirstName = *firstName
+	}
+	if lastName != nil {
+		user.LastName = *lastName
+	}
+	if email != nil {
+		user.Email = *email
+	}
+	if phone != nil {
+		user.Phone = *phone
+	}
+	if locale != nil {
+		user.Locale = *locale
+	}
+	if role != nil {
+		user.Role = *role
+	}
+	if password != nil {
+		user.Password = *password
+	}
+	if err := r.checkUnique(id, user.Email, user.Phone); err != nil {
+		return err
+	}
+	user.UpdatedAt = time.Now()
+	r.users[id] = user
+	return nil
+}
+
+func (r *memoryRepo) Count(ctx context.Context, filters Filters) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.filter(filters)), nil
+}
+
+// filter devuelve los usuarios no borrados que cumplen filters, con la misma
+// búsqueda parcial sin distinguir mayúsculas que applyFilters.
+func (r *memoryRepo) filter(filters Filters) []domain.User {
+	users := []domain.User{}
+	for _, user := range r.users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		if !containsFold(user.FirstName, filters.FirstName) || !containsFold(user.LastName, filters.LastName) {
+			continue
+		}
+		users = append(users, user)
+	}
+	return users
+}
+
+// checkUnique replica los índices únicos de email y teléfono, que en la base
+// también alcanzan a los usuarios borrados.
+func (r *memoryRepo) checkUnique(id, email, phone string) error {
+	for _, other := range r.users {
+		if other.ID == id {
+			continue
+		}
+		if other.Email == email || other.Phone == phone {
+			return apperr.DB(gorm.ErrDuplicatedKey, ErrNotFound)
+		}
+	}
+	return nil
+}
+
+func containsFold(value, substr string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(substr))
+}
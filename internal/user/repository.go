@@ -1,77 +1,91 @@
 package user
 
 import (
+	"context"
 	"fmt"
 	"github.com/raminpz/gocourse_web/internal/domain"
-	"log"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"log/slog"
 
 	"gorm.io/gorm"
 )
 
 type Repository interface {
-	Create(user *domain.User) error
-	GetAll(filters Filters, limit, offset int) ([]domain.User, error)
-	GetByID(id string) (*domain.User, error)
-	Delete(id string) error
-	Update(id string, firstName *string, lastName *string, email *string, phone *string) error
-	Count(filters Filters) (int, error)
+	Create(ctx context.Context, user *domain.User) error
+	GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.User, error)
+	GetByID(ctx context.Context, id string) (*domain.User, error)
+	GetByEmail(ctx context.Context, email string) (*domain.User, error)
+	Delete(ctx context.Context, id string) error
+	Update(ctx context.Context, id string, firstName *string, lastName *string, email *string, phone *string, locale *string, role *domain.Role, password *string) error
+	Count(ctx context.Context, filters Filters) (int, error)
 }
 
 type repo struct {
-	log *log.Logger
+	log *slog.Logger
 	db  *gorm.DB
 }
 
-func NewRepo(log *log.Logger, db *gorm.DB) Repository {
+func NewRepo(log *slog.Logger, db *gorm.DB) Repository {
 	return &repo{
 		log: log,
 		db:  db,
 	}
 }
 
-func (r *repo) Create(user *domain.User) error {
+func (r *repo) Create(ctx context.Context, user *domain.User) error {
 
-	if err := r.db.Create(user).Error; err != nil {
-		r.log.Println(err)
-		return err
+	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
+		r.log.ErrorContext(ctx, "error creating user", "error", err)
+		return apperr.DB(err, ErrNotFound)
 	}
-	r.log.Println("User created with id: ", user.ID)
+	r.log.InfoContext(ctx, "user created", "user_id", user.ID)
 	return nil
 }
 
-func (r *repo) GetAll(filters Filters, limit, offset int) ([]domain.User, error) {
+func (r *repo) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.User, error) {
 	var user []domain.User
-	tx := r.db.Model(&user)
+	tx := r.db.WithContext(ctx).Model(&user)
 	tx = applyFilters(tx, filters)
 	tx = tx.Limit(limit).Offset(offset)
-	tx = applyFilters(tx, filters)
 	result := tx.Order("created_at desc").Find(&user)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, apperr.DB(result.Error, ErrNotFound)
 	}
 	return user, nil
 
 }
 
-func (r *repo) GetByID(id string) (*domain.User, error) {
+func (r *repo) GetByID(ctx context.Context, id string) (*domain.User, error) {
 	user := domain.User{ID: id}
-	result := r.db.First(&user)
+	result := r.db.WithContext(ctx).First(&user)
+	if result.Error != nil {
+		return nil, apperr.DB(result.Error, ErrNotFound)
+	}
+	return &user, nil
+}
+
+func (r *repo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var user domain.User
+	result := r.db.WithContext(ctx).Where("email = ?", email).First(&user)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, apperr.DB(result.Error, ErrNotFound)
 	}
 	return &user, nil
 }
 
-func (r *repo) Delete(id string) error {
+func (r *repo) Delete(ctx context.Context, id string) error {
 	user := domain.User{ID: id}
-	result := r.db.Delete(&user)
+	result := r.db.WithContext(ctx).Delete(&user)
 	if result.Error != nil {
-		return result.Error
+		return apperr.DB(result.Error, ErrNotFound)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
 
-func (r *repo) Update(id string, firstName *string, lastName *string, email *string, phone *string) error {
+func (r *repo) Update(ctx context.Context, id string, firstName *string, lastName *string, email *string, phone *string, locale *string, role *domain.Role, password *string) error {
 	values := make(map[string]interface{})
 	if firstName != nil {
 		values["first_name"] = firstName
@@ -85,9 +99,21 @@ func (r *repo) Update(id string, firstName *string, lastName *string, email *str
 	if phone != nil {
 		values["phone"] = phone
 	}
-	result := r.db.Model(&domain.User{}).Where("id = ?", id).Updates(values)
+	if locale != nil {
+		values["locale"] = locale
+	}
+	if role != nil {
+		values["role"] = role
+	}
+	if password != nil {
+		values["password"] = password
+	}
+	result := r.db.WithContext(ctx).Model(&domain.User{}).Where("id = ?", id).Updates(values)
 	if result.Error != nil {
-		return result.Error
+		return apperr.DB(result.Error, ErrNotFound)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
 	}
 	return nil
 }
@@ -103,13 +129,13 @@ func applyFilters(tx *gorm.DB, filters Filters) *gorm.DB {
 	return tx
 }
 
-func (r *repo) Count(filters Filters) (int, error) {
+func (r *repo) Count(ctx context.Context, filters Filters) (int, error) {
 	var count int64
-	tx := r.db.Model(&domain.User{})
+	tx := r.db.WithContext(ctx).Model(&domain.User{})
 	tx = applyFilters(tx, filters)
 	result := tx.Count(&count)
 	if result.Error != nil {
-		return 0, result.Error
+		return 0, apperr.DB(result.Error, ErrNotFound)
 	}
 	return int(count), nil
 }
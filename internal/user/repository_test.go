@@ -0,0 +1,210 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/testdb"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRepository(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	t.Run("memory", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) user.Repository {
+			return user.NewMemoryRepo(logger)
+		})
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		RepositorySuite(t, func(t *testing.T) user.Repository {
+			return user.NewRepo(logger, testdb.Open(t))
+		})
+	})
+}
+
+// RepositorySuite comprueba el contrato de user.Repository; newRepo devuelve
+// un repositorio vacío en cada llamada.
+func RepositorySuite(t *testing.T, newRepo func(t *testing.T) user.Repository) {
+	ctx := context.Background()
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		u := &domain.User{FirstName: "Ana", LastName: "López", Email: "ana@example.com", Phone: "111"}
+		if err := repo.Create(ctx, u); err != nil {
+			t.Fatal(err)
+		}
+		if u.ID == "" || u.Role != domain.RoleStudent {
+			t.Fatalf("created user has ID %q and role %q, want an ID and role student", u.ID, u.Role)
+		}
+
+		got, err := repo.GetByID(ctx, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Email != u.Email || got.FirstName != "Ana" || got.Role != domain.RoleStudent {
+			t.Errorf("GetByID = %+v", got)
+		}
+		got, err = repo.GetByEmail(ctx, "ana@example.com")
+		if err != nil || got.ID != u.ID {
+			t.Errorf("GetByEmail = %v, %v; want %s", got, err, u.ID)
+		}
+		if _, err := repo.GetByID(ctx, "missing"); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("GetByID(missing) error = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByEmail(ctx, "missing@example.com"); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("GetByEmail(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("unique email and phone", func(t *testing.T) {
+		repo := newRepo(t)
+		existing := createUser(t, repo, "Ana", "ana@example.com", "111", time.Time{})
+		if err := repo.Delete(ctx, existing.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name string
+			user domain.User
+		}{
+			{"same email as a deleted user", domain.User{FirstName: "B", LastName: "B", Email: "ana@example.com", Phone: "222"}},
+			{"same phone as a deleted user", domain.User{FirstName: "B", LastName: "B", Email: "b@example.com", Phone: "111"}},
+			{"same id", domain.User{ID: existing.ID, FirstName: "B", LastName: "B", Email: "c@example.com", Phone: "333"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				u := tt.user
+				err := repo.Create(ctx, &u)
+				if got := apperr.From(err); got.Kind != apperr.KindConflict || got.Code != "duplicated" {
+					t.Errorf("Create error = %v, want a duplicated conflict", err)
+				}
+			})
+		}
+	})
+
+	t.Run("list newest first with filters and pagination", func(t *testing.T) {
+		repo := newRepo(t)
+		base := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+		names := []string{"Ana", "Bruno", "Anabel", "Carla"}
+		ids := make([]string, len(names))
+		for i, name := range names {
+			ids[i] = createUser(t, repo, name, fmt.Sprintf("u%d@example.com", i), fmt.Sprint(i), base.Add(time.Duration(i)*time.Hour)).ID
+		}
+		deleted := createUser(t, repo, "Ana", "gone@example.com", "999", base.Add(time.Minute))
+		if err := repo.Delete(ctx, deleted.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name          string
+			filters       user.Filters
+			limit, offset int
+			want          []string
+		}{
+			{"first page", user.Filters{}, 2, 0, []string{ids[3], ids[2]}},
+			{"last page", user.Filters{}, 2, 2, []string{ids[1], ids[0]}},
+			{"past the end", user.Filters{}, 2, 10, nil},
+			{"zero limit", user.Filters{}, 0, 0, nil},
+			{"negative limit", user.Filters{}, -1, 0, []string{ids[3], ids[2], ids[1], ids[0]}},
+			{"negative limit with offset", user.Filters{}, -1, 1, []string{ids[2], ids[1], ids[0]}},
+			{"first name, any case", user.Filters{FirstName: "aNa"}, 10, 0, []string{ids[2], ids[0]}},
+			{"last name", user.Filters{LastName: "lóp"}, 10, 0, []string{ids[3], ids[2], ids[1], ids[0]}},
+			{"no match", user.Filters{LastName: "zzz"}, 10, 0, nil},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				users, err := repo.GetAll(ctx, tt.filters, tt.limit, tt.offset)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := userIDs(users); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+					t.Errorf("GetAll = %v, want %v", got, tt.want)
+				}
+			})
+		}
+
+		count, err := repo.Count(ctx, user.Filters{FirstName: "ana"})
+		if err != nil || count != 2 {
+			t.Errorf("Count = %d, %v; want 2", count, err)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		repo := newRepo(t)
+		u := createUser(t, repo, "Ana", "ana@example.com", "111", time.Time{})
+		other := createUser(t, repo, "Bruno", "bruno@example.com", "222", time.Time{})
+
+		name, locale, role := "Anita", "es", domain.RoleInstructor
+		if err := repo.Update(ctx, u.ID, &name, nil, nil, nil, &locale, &role, nil); err != nil {
+			t.Fatal(err)
+		}
+		got, err := repo.GetByID(ctx, u.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.FirstName != "Anita" || got.Locale != "es" || got.Role != domain.RoleInstructor || got.LastName != "López" || got.Email != "ana@example.com" {
+			t.Errorf("after Update got %+v", got)
+		}
+
+		// Si el email choca no se aplica nada, tampoco el rol.
+		taken, admin := other.Email, domain.RoleAdmin
+		err = repo.Update(ctx, u.ID, nil, nil, &taken, nil, nil, &admin, nil)
+		if got := apperr.From(err); got.Kind != apperr.KindConflict {
+			t.Errorf("Update to a taken email error = %v, want a conflict", err)
+		}
+		if got, _ := repo.GetByID(ctx, u.ID); got.Role != domain.RoleInstructor || got.Email != "ana@example.com" {
+			t.Errorf("after a failed Update got role %q and email %q, want them unchanged", got.Role, got.Email)
+		}
+
+		if err := repo.Update(ctx, "missing", &name, nil, nil, nil, nil, nil, nil); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("Update(missing) error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("delete is soft and final", func(t *testing.T) {
+		repo := newRepo(t)
+		u := createUser(t, repo, "Ana", "ana@example.com", "111", time.Time{})
+		if err := repo.Delete(ctx, u.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.GetByID(ctx, u.ID); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("GetByID after Delete error = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByEmail(ctx, u.Email); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("GetByEmail after Delete error = %v, want ErrNotFound", err)
+		}
+		name := "Anita"
+		if err := repo.Update(ctx, u.ID, &name, nil, nil, nil, nil, nil, nil); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("Update after Delete error = %v, want ErrNotFound", err)
+		}
+		if err := repo.Delete(ctx, u.ID); !errors.Is(err, user.ErrNotFound) {
+			t.Errorf("second Delete error = %v, want ErrNotFound", err)
+		}
+		if count, _ := repo.Count(ctx, user.Filters{}); count != 0 {
+			t.Errorf("Count after Delete = %d, want 0", count)
+		}
+	})
+}
+
+// createUser crea un usuario López; con createdAt cero toma la hora actual.
+func createUser(t *testing.T, repo user.Repository, firstName, email, phone string, createdAt time.Time) *domain.User {
+	t.Helper()
+	u := &domain.User{FirstName: firstName, LastName: "López", Email: email, Phone: phone, CreatedAt: createdAt}
+	if err := repo.Create(context.Background(), u); err != nil {
+		t.Fatalf("create user %s: %v", email, err)
+	}
+	return u
+}
+
+func userIDs(users []domain.User) []string {
+	var ids []string
+	for _, u := range users {
+		ids = append(ids, u.ID)
+	}
+	return ids
+}
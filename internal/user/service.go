@@ -1,8 +1,14 @@
 package user
 
 import (
+	"context"
+	"errors"
 	"github.com/raminpz/gocourse_web/internal/domain"
-	"log"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/metrics"
+	"github.com/raminpz/gocourse_web/pkg/tracing"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
 )
 
 type (
@@ -11,65 +17,167 @@ type (
 		LastName  string
 	}
 	Service interface {
-		Create(firstName, lastName, email, phone string) (*domain.User, error)
-		Get(id string) (*domain.User, error)
-		GetAll(filters Filters, limit, offset int) ([]domain.User, error)
-		Delete(id string) error
-		Update(id string, firstName *string, lastName *string, email *string, phone *string) error
-		Count(filters Filters) (int, error)
+		Create(ctx context.Context, firstName, lastName, email, phone, password string, role domain.Role, locale string) (*domain.User, error)
+		Get(ctx context.Context, id string) (*domain.User, error)
+		GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.User, error)
+		Delete(ctx context.Context, id string) error
+		Update(ctx context.Context, id string, firstName *string, lastName *string, email *string, phone *string, locale *string, role *domain.Role, password *string, currentPassword *string) error
+		Count(ctx context.Context, filters Filters) (int, error)
+		Login(ctx context.Context, email, password string) (*domain.User, error)
 	}
 	service struct {
-		log  *log.Logger
+		log  *slog.Logger
 		repo Repository
 	}
 )
 
-func NewService(log *log.Logger, repo Repository) Service {
+var tracer = tracing.Tracer("internal/user")
+
+var (
+	ErrNotFound                = apperr.NotFound("user_not_found", "user does not exist")
+	ErrInvalidCredentials      = apperr.Unauthorized("invalid_credentials", "invalid email or password")
+	ErrCurrentPasswordRequired = apperr.Validation("current_password_required", "current password is required")
+	ErrWrongCurrentPassword    = apperr.Forbidden("wrong_current_password", "current password is incorrect")
+	ErrInvalidRole             = apperr.Validation("invalid_role", "role must be one of admin, instructor or student")
+	ErrPasswordTooLong         = apperr.Validation("password_too_long", "password must be at most 72 bytes long")
+)
+
+func NewService(log *slog.Logger, repo Repository) Service {
 	return &service{
 		log:  log,
 		repo: repo,
 	}
 }
 
-func (s service) Create(firstName, lastName, email, phone string) (*domain.User, error) {
-	s.log.Println("Create user service")
+func (s service) Create(ctx context.Context, firstName, lastName, email, phone, password string, role domain.Role, locale string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "user.Create")
+	defer span.End()
+
+	s.log.DebugContext(ctx, "creating user")
+	if role == "" {
+		role = domain.RoleStudent
+	}
+	if !role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
 	user := domain.User{
 		FirstName: firstName,
 		LastName:  lastName,
 		Email:     email,
 		Phone:     phone,
+		Password:  hash,
+		Role:      role,
+		Locale:    locale,
 	}
-	if err := s.repo.Create(&user); err != nil {
+	if err := s.repo.Create(ctx, &user); err != nil {
 		return nil, err
 	}
+	metrics.UsersCreated.Inc()
 
 	return &user, nil
 }
 
-func (s service) GetAll(filters Filters, limit, offset int) ([]domain.User, error) {
-	users, err := s.repo.GetAll(filters, limit, offset)
+func (s service) GetAll(ctx context.Context, filters Filters, limit, offset int) ([]domain.User, error) {
+	ctx, span := tracer.Start(ctx, "user.GetAll")
+	defer span.End()
+
+	users, err := s.repo.GetAll(ctx, filters, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (s service) Get(id string) (*domain.User, error) {
-	user, err := s.repo.GetByID(id)
+func (s service) Get(ctx context.Context, id string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "user.Get")
+	defer span.End()
+
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (s service) Delete(id string) error {
-	return s.repo.Delete(id)
+func (s service) Delete(ctx context.Context, id string) error {
+	ctx, span := tracer.Start(ctx, "user.Delete")
+	defer span.End()
+
+	return s.repo.Delete(ctx, id)
 }
 
-func (s service) Update(id string, firstName *string, lastName *string, email *string, phone *string) error {
-	return s.repo.Update(id, firstName, lastName, email, phone)
+// Update valida todo antes de escribir y aplica los cambios, el rol incluido,
+// en una sola actualización: si algo falla no queda nada a medias.
+func (s service) Update(ctx context.Context, id string, firstName *string, lastName *string, email *string, phone *string, locale *string, role *domain.Role, password *string, currentPassword *string) error {
+	ctx, span := tracer.Start(ctx, "user.Update")
+	defer span.End()
+
+	if role != nil && !role.Valid() {
+		return ErrInvalidRole
+	}
+	var hash *string
+	if password != nil {
+		if currentPassword == nil {
+			return ErrCurrentPasswordRequired
+		}
+		user, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(*currentPassword)) != nil {
+			return ErrWrongCurrentPassword
+		}
+		newHash, err := hashPassword(*password)
+		if err != nil {
+			return err
+		}
+		hash = &newHash
+	}
+	return s.repo.Update(ctx, id, firstName, lastName, email, phone, locale, role, hash)
 }
 
-func (s service) Count(filters Filters) (int, error) {
-	return s.repo.Count(filters)
+func (s service) Count(ctx context.Context, filters Filters) (int, error) {
+	ctx, span := tracer.Start(ctx, "user.Count")
+	defer span.End()
+
+	return s.repo.Count(ctx, filters)
+}
+
+func (s service) Login(ctx context.Context, email, password string) (*domain.User, error) {
+	ctx, span := tracer.Start(ctx, "user.Login")
+	defer span.End()
+
+	user, err := s.repo.GetByEmail(ctx, email)
+	if errors.Is(err, ErrNotFound) {
+		// Comparamos igual contra un hash vacío para no revelar si el email existe
+		// por la diferencia en el tiempo de respuesta.
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return user, nil
+}
+
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// hashPassword rechaza las contraseñas de más de 72 bytes, el límite de
+// bcrypt, aunque tengan menos de 72 caracteres.
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", ErrPasswordTooLong
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
 }
@@ -0,0 +1,192 @@
+package user_test
+
+import (
+	"context"
+	"errors"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"golang.org/x/crypto/bcrypt"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// failingRepo es un repositorio en memoria cuyas búsquedas por email fallan
+// con err, como cuando no responde la base.
+type failingRepo struct {
+	user.Repository
+	err error
+}
+
+func (r failingRepo) GetByEmail(context.Context, string) (*domain.User, error) {
+	return nil, r.err
+}
+
+// newRepo devuelve un repositorio en memoria con ana@example.com, cuya
+// contraseña es "secret-pass".
+func newRepo(t *testing.T) user.Repository {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := user.NewMemoryRepo(slog.New(slog.DiscardHandler))
+	ana := &domain.User{ID: "ana", FirstName: "Ana", Email: "ana@example.com", Phone: "1", Password: string(hash), Role: domain.RoleStudent}
+	if err := repo.Create(context.Background(), ana); err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func newService(repo user.Repository) user.Service {
+	return user.NewService(slog.New(slog.DiscardHandler), repo)
+}
+
+// stored devuelve a ana tal como quedó en repo.
+func stored(t *testing.T, repo user.Repository) *domain.User {
+	t.Helper()
+	u, err := repo.GetByID(context.Background(), "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestServiceCreate(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     domain.Role
+		password string
+		wantRole domain.Role
+		wantErr  error
+	}{
+		{name: "student by default", password: "new-secret", wantRole: domain.RoleStudent},
+		{name: "instructor", role: domain.RoleInstructor, password: "new-secret", wantRole: domain.RoleInstructor},
+		{name: "unknown role", role: "owner", password: "new-secret", wantErr: user.ErrInvalidRole},
+		{name: "password over 72 bytes", password: strings.Repeat("ñ", 40), wantErr: user.ErrPasswordTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			u, err := newService(repo).Create(context.Background(), "Bea", "Ruiz", "bea@example.com", "2", tt.password, tt.role, "es")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create error = %v, want %v", err, tt.wantErr)
+			}
+			got, getErr := repo.GetByEmail(context.Background(), "bea@example.com")
+			if tt.wantErr != nil {
+				if !errors.Is(getErr, user.ErrNotFound) {
+					t.Errorf("Create stored %+v after failing", got)
+				}
+				return
+			}
+			if getErr != nil || got.ID != u.ID || got.Role != tt.wantRole || got.Locale != "es" {
+				t.Fatalf("stored %+v, %v; want the returned user %+v with role %s", got, getErr, u, tt.wantRole)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(got.Password), []byte(tt.password)) != nil {
+				t.Errorf("stored password %q is not a hash of the given one", got.Password)
+			}
+		})
+	}
+}
+
+func TestServiceUpdatePassword(t *testing.T) {
+	tests := []struct {
+		name              string
+		id                string
+		password, current *string
+		wantErr           error
+	}{
+		{name: "without a new password", id: "ana"},
+		{name: "with the current password", id: "ana", password: ptr("new-secret"), current: ptr("secret-pass")},
+		{name: "without the current password", id: "ana", password: ptr("new-secret"), wantErr: user.ErrCurrentPasswordRequired},
+		{name: "with a wrong current password", id: "ana", password: ptr("new-secret"), current: ptr("nope"), wantErr: user.ErrWrongCurrentPassword},
+		{name: "password over 72 bytes", id: "ana", password: ptr(strings.Repeat("ñ", 40)), current: ptr("secret-pass"), wantErr: user.ErrPasswordTooLong},
+		{name: "missing user", id: "missing", password: ptr("new-secret"), current: ptr("secret-pass"), wantErr: user.ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			err := newService(repo).Update(context.Background(), tt.id, ptr("Anita"), nil, nil, nil, nil, nil, tt.password, tt.current)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update error = %v, want %v", err, tt.wantErr)
+			}
+
+			u := stored(t, repo)
+			wantName, wantPassword := "Anita", "secret-pass"
+			if tt.wantErr != nil {
+				wantName = "Ana"
+			} else if tt.password != nil {
+				wantPassword = *tt.password
+			}
+			if u.FirstName != wantName {
+				t.Errorf("first name = %q, want %q", u.FirstName, wantName)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(wantPassword)) != nil {
+				t.Errorf("stored password is not a hash of %q", wantPassword)
+			}
+		})
+	}
+}
+
+func TestServiceUpdateRole(t *testing.T) {
+	tests := []struct {
+		name              string
+		role              domain.Role
+		password, current *string
+		wantErr           error
+	}{
+		{name: "valid role", role: domain.RoleAdmin},
+		{name: "unknown role", role: "owner", wantErr: user.ErrInvalidRole},
+		{name: "with a wrong current password", role: domain.RoleAdmin, password: ptr("new-secret"), current: ptr("nope"), wantErr: user.ErrWrongCurrentPassword},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			err := newService(repo).Update(context.Background(), "ana", nil, nil, nil, nil, nil, &tt.role, tt.password, tt.current)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Update error = %v, want %v", err, tt.wantErr)
+			}
+			// El rol se guarda en la misma escritura que el resto, o no se guarda.
+			want := tt.role
+			if tt.wantErr != nil {
+				want = domain.RoleStudent
+			}
+			if got := stored(t, repo).Role; got != want {
+				t.Errorf("stored role %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestServiceLogin(t *testing.T) {
+	errDB := errors.New("connection refused")
+	tests := []struct {
+		name, email, password string
+		repoErr               error
+		wantErr               error
+	}{
+		{name: "valid", email: "ana@example.com", password: "secret-pass"},
+		{name: "wrong password", email: "ana@example.com", password: "nope", wantErr: user.ErrInvalidCredentials},
+		{name: "unknown email", email: "bea@example.com", password: "secret-pass", wantErr: user.ErrInvalidCredentials},
+		{name: "database failure", email: "ana@example.com", password: "secret-pass", repoErr: errDB, wantErr: errDB},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			if tt.repoErr != nil {
+				repo = failingRepo{Repository: repo, err: tt.repoErr}
+			}
+			u, err := newService(repo).Login(context.Background(), tt.email, tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Login error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && u.ID != "ana" {
+				t.Errorf("Login = %+v, want ana", u)
+			}
+		})
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -1,60 +1,120 @@
 package main
 
 import (
-	"github.com/raminpz/gocourse_web/internal/course"
-	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"context"
+	"fmt"
 	"github.com/raminpz/gocourse_web/pkg/bootstrap"
-	"log"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/logging"
+	"github.com/raminpz/gocourse_web/pkg/metrics"
+	"github.com/raminpz/gocourse_web/pkg/tracing"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
-
-	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
-	"github.com/raminpz/gocourse_web/internal/user"
 )
 
 func main() {
-	router := mux.NewRouter()
+	cfg, err := config.Load(configSections(os.Args[1:])...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	loggers, err := bootstrap.InitLoger(cfg.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	l := loggers.Root()
 
-	_ = godotenv.Load()
-	l := bootstrap.InitLoger()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(cfg.Database, os.Args[2:], loggers.For("migrate")); err != nil {
+				fatal(l, "Migration failed", err)
+			}
+			return
+		case "config":
+			// Muestra la configuración efectiva sin los secretos.
+			cfg.Print(os.Stdout)
+			return
+		}
+	}
 
-	db, err := bootstrap.DBConnection()
+	store, err := openStorage(cfg, loggers)
 	if err != nil {
-		l.Fatal("Failed to connect to database: ", err)
+		fatal(l, "Failed to open storage", err)
 	}
 
-	userRepo := user.NewRepo(l, db)
-	userSrv := user.NewService(l, userRepo)
-	userEnd := user.MakeEndpoints(userSrv)
+	signer, err := bootstrap.InitSigner(cfg.JWT)
+	if err != nil {
+		fatal(l, "Failed to configure token signer", err)
+	}
+	if err := bootstrap.InitResponseVersion(cfg.API); err != nil {
+		fatal(l, "Invalid API version", err)
+	}
+	bootstrap.InitPaginator(cfg.Paginator)
 
-	courseRepo := course.NewRepo(db, l)
-	courseSrv := course.NewService(courseRepo, l)
-	courseEnd := course.MakeEndpoints(courseSrv)
+	router, healthSrv := newRouter(cfg, store, signer, loggers)
 
-	enrollRepo := enrollment.NewRepo(db, l)
-	enrollSrv := enrollment.NewService(enrollRepo, l, userSrv, courseSrv)
-	enrollEnd := enrollment.MakeEndpoints(enrollSrv)
+	srv := &http.Server{
+		// Los middlewares envuelven al router para registrar también las rutas inexistentes.
+		Handler:      logging.Middleware(loggers.For("http"))(metrics.Middleware(router)),
+		Addr:         cfg.Server.Addr,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
 
-	router.HandleFunc("/users", userEnd.Create).Methods("POST")
-	router.HandleFunc("/users/{id}", userEnd.Get).Methods("GET")
-	router.HandleFunc("/users", userEnd.GetAll).Methods("GET")
-	router.HandleFunc("/users/{id}", userEnd.Update).Methods("PATCH")
-	router.HandleFunc("/users/{id}", userEnd.Delete).Methods("DELETE")
+	ln, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		fatal(l, "Failed to listen", err)
+	}
 
-	router.HandleFunc("/courses", courseEnd.Create).Methods("POST")
-	router.HandleFunc("/courses/{id}", courseEnd.Get).Methods("GET")
-	router.HandleFunc("/courses", courseEnd.GetAll).Methods("GET")
-	router.HandleFunc("/courses/{id}", courseEnd.Update).Methods("PATCH")
-	router.HandleFunc("/courses/{id}", courseEnd.Delete).Methods("DELETE")
+	// SIGINT o SIGTERM apagan el servidor drenando las peticiones en curso.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := 0
+	if err := serve(ctx, srv, ln, cfg.Server, healthSrv.Shutdown, l); err != nil {
+		l.Error("Server error", "error", err)
+		code = 1
+	}
+	stop()
 
-	router.HandleFunc("/enrollments", enrollEnd.Create).Methods("POST")
+	if err := store.Close(); err != nil {
+		l.Error("Failed to close database", "error", err)
+		code = 1
+	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(flushCtx); err != nil {
+		l.Error("Failed to flush traces", "error", err)
+		code = 1
+	}
+	cancel()
+	// El logger escribe en stdout; Sync vacía lo pendiente si es un archivo.
+	_ = os.Stdout.Sync()
+	os.Exit(code)
+}
 
-	srv := &http.Server{
-		Handler:      router,
-		Addr:         "127.0.0.1:8000",
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 5 * time.Second,
+// configSections devuelve las secciones de la configuración que se validan
+// para los argumentos args; el servidor y config usan todas.
+func configSections(args []string) []string {
+	if len(args) > 0 && args[0] == "migrate" {
+		return migrateSections(args[1:])
 	}
-	log.Fatal(srv.ListenAndServe())
+	return nil
+}
+
+// fatal registra err y termina el proceso con código 1.
+func fatal(l *slog.Logger, msg string, err error) {
+	l.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/raminpz/gocourse_web/pkg/bootstrap"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/migrate"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+)
+
+const migrateUsage = `usage: gocourse_web migrate <command>
+
+commands:
+  status          lista las migraciones y si están aplicadas
+  up              aplica todas las migraciones pendientes
+  down [N]        revierte las últimas N migraciones (1 por defecto)
+  create NAME     crea los archivos up/down de una nueva migración`
+
+// migrateSections son las secciones de la configuración que usa el subcomando
+// migrate: create solo escribe archivos y el resto necesita la base.
+func migrateSections(args []string) []string {
+	flags, _ := migrateFlags()
+	flags.SetOutput(io.Discard)
+	if flags.Parse(args) == nil && flags.Arg(0) == "create" {
+		return []string{"log", "tracing"}
+	}
+	return []string{"log", "tracing", "database"}
+}
+
+func migrateFlags() (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dir := flags.String("dir", "migrations", "directorio donde create escribe las migraciones")
+	return flags, dir
+}
+
+// runMigrate implementa el subcomando migrate.
+func runMigrate(cfg config.Database, args []string, l *slog.Logger) error {
+	flags, dir := migrateFlags()
+	flags.Usage = func() { fmt.Fprintln(os.Stderr, migrateUsage) }
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("migrate: command is required")
+	}
+
+	command, rest := flags.Arg(0), flags.Args()[1:]
+	if command == "create" {
+		if len(rest) != 1 {
+			return errors.New("migrate create: NAME is required")
+		}
+		files, err := migrate.Create(*dir, rest[0])
+		for _, file := range files {
+			fmt.Println("created", file)
+		}
+		return err
+	}
+
+	db, err := bootstrap.OpenDB(cfg)
+	if err != nil {
+		return err
+	}
+	migrator, err := bootstrap.Migrator(db, l)
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+	case "up":
+		count, err := migrator.Up()
+		fmt.Printf("applied %d migrations\n", count)
+		return err
+	case "down":
+		n := 1
+		if len(rest) > 0 {
+			if n, err = strconv.Atoi(rest[0]); err != nil || n < 1 {
+				return fmt.Errorf("migrate down: invalid N %q", rest[0])
+			}
+		}
+		count, err := migrator.Down(n)
+		fmt.Printf("reverted %d migrations\n", count)
+		return err
+	default:
+		flags.Usage()
+		return fmt.Errorf("migrate: unknown command %q", command)
+	}
+}
@@ -0,0 +1,13 @@
+// Package migrations contiene los scripts SQL versionados de cada motor de
+// base de datos. Cada versión tiene un archivo NNNN_nombre.up.sql y otro
+// NNNN_nombre.down.sql en el directorio del motor (mysql, postgres, sqlite).
+//
+// 0001_init es el esquema que creaba AutoMigrate y usa IF NOT EXISTS, así que
+// una base creada por AutoMigrate queda registrada como versión 1 y las
+// versiones siguientes le agregan lo que falta y completan los datos.
+package migrations
+
+import "embed"
+
+//go:embed mysql/*.sql postgres/*.sql sqlite/*.sql
+var FS embed.FS
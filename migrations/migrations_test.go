@@ -0,0 +1,163 @@
+package migrations_test
+
+import (
+	"context"
+	"errors"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/migrations"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/bootstrap"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/migrate"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// Copias de los modelos que AutoMigrate usaba para crear las bases antes de
+// las migraciones versionadas.
+type (
+	baselineUser struct {
+		ID        string `gorm:"type:char(36);primaryKey"`
+		FirstName string `gorm:"type:char(50);not null"`
+		LastName  string `gorm:"type:char(50);not null"`
+		Email     string `gorm:"type:char(50);not null; unique"`
+		Phone     string `gorm:"type:char(11);not null; unique"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+	}
+
+	baselineCourse struct {
+		ID        string `gorm:"type:char(36);not null;primaryKey;unique"`
+		Name      string `gorm:"type:char(50);not null"`
+		StartDate time.Time
+		EndDate   time.Time
+		CreatedAt time.Time
+		UpdatedAt time.Time
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+	}
+
+	baselineEnrollment struct {
+		ID        string          `gorm:"type:char(36);not null;primary_key;unique_index"`
+		UserID    string          `gorm:"type:char(36)"`
+		User      *baselineUser   `gorm:"foreignKey:UserID"`
+		CourseID  string          `gorm:"type:char(36);not null"`
+		Course    *baselineCourse `gorm:"foreignKey:CourseID"`
+		Status    string          `gorm:"type:char(2)"`
+		CreatedAt *time.Time
+		UpdatedAt *time.Time
+	}
+)
+
+func (baselineUser) TableName() string       { return "users" }
+func (baselineCourse) TableName() string     { return "courses" }
+func (baselineEnrollment) TableName() string { return "enrollments" }
+
+// TestUpgradeAutoMigrateSchema migra una base creada por AutoMigrate y con
+// datos, como las que ya estaban en uso antes de las migraciones versionadas.
+func TestUpgradeAutoMigrateSchema(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.DiscardHandler)
+	db, err := bootstrap.OpenDB(config.Database{Driver: "sqlite", QueryTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Logger = logger.Discard
+	t.Cleanup(func() { bootstrap.CloseDB(db) })
+
+	if err := db.AutoMigrate(&baselineUser{}, &baselineCourse{}, &baselineEnrollment{}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().AddDate(0, 1, 0)
+	at := func(days int) *time.Time {
+		t := time.Now().AddDate(0, 0, days)
+		return &t
+	}
+	for _, row := range []interface{}{
+		&baselineUser{ID: "ana", FirstName: "Ana", LastName: "Ruiz", Email: "ana@example.com", Phone: "1"},
+		&baselineUser{ID: "bea", FirstName: "Bea", LastName: "Gil", Email: "bea@example.com", Phone: "2"},
+		&baselineCourse{ID: "go", Name: "Go", StartDate: start, EndDate: start.AddDate(0, 3, 0)},
+		&baselineCourse{ID: "rust", Name: "Rust", StartDate: start, EndDate: start.AddDate(0, 3, 0)},
+		// ana se inscribió dos veces en go; queda vigente la última.
+		&baselineEnrollment{ID: "old", UserID: "ana", CourseID: "go", Status: "P", CreatedAt: at(-2)},
+		&baselineEnrollment{ID: "new", UserID: "ana", CourseID: "go", Status: "P", CreatedAt: at(-1)},
+		&baselineEnrollment{ID: "blank", UserID: "bea", CourseID: "go", CreatedAt: at(-1)},
+		&baselineEnrollment{ID: "rejected", UserID: "bea", CourseID: "rust", Status: "R", CreatedAt: at(-1)},
+	} {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrator, err := migrate.New(db, migrations.FS, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied, latest, _ := migrator.Version(ctx); applied != latest {
+		t.Fatalf("version = %d, want %d", applied, latest)
+	}
+
+	u, err := user.NewRepo(log, db).GetByID(ctx, "ana")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.FirstName != "Ana" || u.Role != domain.RoleStudent || u.Password != "" {
+		t.Errorf("user = %+v, want Ana as a student without a password", u)
+	}
+
+	var rows []struct {
+		ID     string
+		Status domain.EnrollmentStatus
+		Active *bool
+	}
+	if err := db.Table("enrollments").Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]struct {
+		status domain.EnrollmentStatus
+		active bool
+	}{
+		"blank":    {domain.EnrollmentPending, true},
+		"new":      {domain.EnrollmentPending, true},
+		"old":      {domain.EnrollmentWithdrawn, false},
+		"rejected": {domain.EnrollmentRejected, false},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("enrollments = %+v, want %d", rows, len(want))
+	}
+	for _, row := range rows {
+		w := want[row.ID]
+		if row.Status != w.status || (row.Active != nil) != w.active {
+			t.Errorf("enrollment %s = %s active %v, want %s active %v", row.ID, row.Status, row.Active, w.status, w.active)
+		}
+	}
+
+	enrollments := enrollment.NewRepo(db, log)
+	if e, err := enrollments.GetActive(ctx, "ana", "go"); err != nil || e.ID != "new" {
+		t.Errorf("GetActive(ana, go) = %v, %v; want new", e, err)
+	}
+	err = enrollments.Create(ctx, &domain.Enrollment{UserID: "ana", CourseID: "go", Status: domain.EnrollmentPending})
+	if !errors.Is(err, apperr.Conflict("duplicated", "")) {
+		t.Errorf("second active enrollment error = %v, want duplicated", err)
+	}
+
+	// Revertir todo deja la base vacía y se puede volver a migrar.
+	if reverted, err := migrator.Down(applied); err != nil || reverted != applied {
+		t.Fatalf("Down(%d) = %d, %v", applied, reverted, err)
+	}
+	if db.Migrator().HasTable("users") {
+		t.Error("users still exists after reverting every migration")
+	}
+	if again, err := migrator.Up(); err != nil || again != applied {
+		t.Errorf("Up after Down = %d, %v; want %d", again, err, applied)
+	}
+}
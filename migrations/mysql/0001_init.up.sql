@@ -0,0 +1,42 @@
+-- Esquema que creaba AutoMigrate antes de las migraciones versionadas. Con IF
+-- NOT EXISTS, "migrate up" adopta esas bases y las actualiza con 0002 en
+-- adelante.
+CREATE TABLE IF NOT EXISTS users (
+    id CHAR(36),
+    first_name CHAR(50) NOT NULL,
+    last_name CHAR(50) NOT NULL,
+    email CHAR(50) NOT NULL,
+    phone CHAR(11) NOT NULL,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_users_deleted_at (deleted_at),
+    CONSTRAINT uni_users_email UNIQUE (email),
+    CONSTRAINT uni_users_phone UNIQUE (phone)
+);
+
+CREATE TABLE IF NOT EXISTS courses (
+    id CHAR(36) NOT NULL,
+    name CHAR(50) NOT NULL,
+    start_date DATETIME(3) NULL,
+    end_date DATETIME(3) NULL,
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    deleted_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_courses_deleted_at (deleted_at),
+    CONSTRAINT uni_courses_id UNIQUE (id)
+);
+
+CREATE TABLE IF NOT EXISTS enrollments (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36),
+    course_id CHAR(36) NOT NULL,
+    status CHAR(2),
+    created_at DATETIME(3) NULL,
+    updated_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    CONSTRAINT fk_enrollments_user FOREIGN KEY (user_id) REFERENCES users (id),
+    CONSTRAINT fk_enrollments_course FOREIGN KEY (course_id) REFERENCES courses (id)
+);
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS refresh_tokens;
+
+ALTER TABLE users
+    DROP COLUMN locale,
+    DROP COLUMN role,
+    DROP COLUMN password,
+    MODIFY first_name CHAR(50) NOT NULL,
+    MODIFY last_name CHAR(50) NOT NULL,
+    MODIFY email CHAR(50) NOT NULL,
+    MODIFY phone CHAR(11) NOT NULL;
@@ -0,0 +1,23 @@
+-- Contraseñas, roles, idioma y refresh tokens. Los usuarios que ya existían
+-- quedan como estudiantes y sin contraseña.
+ALTER TABLE users
+    MODIFY first_name VARCHAR(50) NOT NULL,
+    MODIFY last_name VARCHAR(50) NOT NULL,
+    MODIFY email VARCHAR(50) NOT NULL,
+    MODIFY phone VARCHAR(11) NOT NULL,
+    ADD COLUMN password VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'student',
+    ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT '';
+
+CREATE TABLE refresh_tokens (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at DATETIME(3) NOT NULL,
+    revoked_at DATETIME(3) NULL,
+    replaced_by CHAR(36) NULL,
+    created_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_refresh_tokens_token_hash (token_hash),
+    KEY idx_refresh_tokens_user_id (user_id)
+);
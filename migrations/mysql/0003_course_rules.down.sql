@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS course_instructors;
+DROP TABLE IF EXISTS course_prerequisites;
+
+ALTER TABLE courses
+    DROP COLUMN enrollment_close,
+    DROP COLUMN enrollment_open,
+    DROP COLUMN capacity,
+    MODIFY name CHAR(50) NOT NULL;
@@ -0,0 +1,24 @@
+-- Cupo, ventana de inscripción, prerrequisitos e instructores. Los cursos que
+-- ya existían quedan sin límite de lugares y con la inscripción abierta hasta
+-- que empiezan.
+ALTER TABLE courses
+    MODIFY name VARCHAR(50) NOT NULL,
+    ADD COLUMN capacity BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN enrollment_open DATETIME(3) NULL,
+    ADD COLUMN enrollment_close DATETIME(3) NULL;
+
+CREATE TABLE course_prerequisites (
+    course_id CHAR(36) NOT NULL,
+    prerequisite_id CHAR(36) NOT NULL,
+    PRIMARY KEY (course_id, prerequisite_id),
+    CONSTRAINT fk_course_prerequisites_course FOREIGN KEY (course_id) REFERENCES courses (id),
+    CONSTRAINT fk_course_prerequisites_prerequisite FOREIGN KEY (prerequisite_id) REFERENCES courses (id)
+);
+
+CREATE TABLE course_instructors (
+    course_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    PRIMARY KEY (course_id, user_id),
+    CONSTRAINT fk_course_instructors_course FOREIGN KEY (course_id) REFERENCES courses (id),
+    CONSTRAINT fk_course_instructors_user FOREIGN KEY (user_id) REFERENCES users (id)
+);
@@ -0,0 +1,10 @@
+-- MySQL puede haber borrado el índice que creó para fk_enrollments_user al
+-- aparecer idx_enrollment_user_course; sin otro índice sobre user_id no deja
+-- borrar este.
+CREATE INDEX idx_enrollments_user_id ON enrollments (user_id);
+DROP INDEX idx_enrollment_user_course ON enrollments;
+
+ALTER TABLE enrollments
+    DROP COLUMN waitlist_position,
+    DROP COLUMN active,
+    MODIFY status CHAR(2) NULL;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS refresh_tokens;
+
+ALTER TABLE users
+    DROP COLUMN locale,
+    DROP COLUMN role,
+    DROP COLUMN password,
+    ALTER COLUMN first_name TYPE CHAR(50),
+    ALTER COLUMN last_name TYPE CHAR(50),
+    ALTER COLUMN email TYPE CHAR(50),
+    ALTER COLUMN phone TYPE CHAR(11);
@@ -0,0 +1,24 @@
+-- Contraseñas, roles, idioma y refresh tokens. Los usuarios que ya existían
+-- quedan como estudiantes y sin contraseña. Pasar de CHAR a VARCHAR quita los
+-- espacios de relleno.
+ALTER TABLE users
+    ALTER COLUMN first_name TYPE VARCHAR(50),
+    ALTER COLUMN last_name TYPE VARCHAR(50),
+    ALTER COLUMN email TYPE VARCHAR(50),
+    ALTER COLUMN phone TYPE VARCHAR(11),
+    ADD COLUMN password VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'student',
+    ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT '';
+
+CREATE TABLE refresh_tokens (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ NULL,
+    replaced_by CHAR(36) NULL,
+    created_at TIMESTAMPTZ NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
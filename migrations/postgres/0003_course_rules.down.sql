@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS course_instructors;
+DROP TABLE IF EXISTS course_prerequisites;
+
+ALTER TABLE courses
+    DROP COLUMN enrollment_close,
+    DROP COLUMN enrollment_open,
+    DROP COLUMN capacity,
+    ALTER COLUMN name TYPE CHAR(50);
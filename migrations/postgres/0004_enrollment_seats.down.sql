@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_enrollment_user_course;
+
+ALTER TABLE enrollments
+    DROP COLUMN waitlist_position,
+    DROP COLUMN active,
+    ALTER COLUMN status TYPE CHAR(2);
@@ -0,0 +1,24 @@
+-- Lugares, lista de espera y una sola inscripción vigente por usuario y curso.
+-- Las inscripciones sin estado conocido quedan pendientes y, si un usuario
+-- tenía varias vigentes en el mismo curso, se conserva la más reciente y las
+-- demás pasan a retiradas. Pasar de CHAR a VARCHAR quita el espacio de relleno
+-- de los códigos de estado.
+ALTER TABLE enrollments
+    ALTER COLUMN status TYPE VARCHAR(2),
+    ADD COLUMN active BOOLEAN NULL,
+    ADD COLUMN waitlist_position BIGINT NULL;
+
+UPDATE enrollments SET status = 'P'
+WHERE status IS NULL OR status NOT IN ('P', 'A', 'S', 'C', 'W', 'R', 'L');
+
+UPDATE enrollments SET active = TRUE WHERE status NOT IN ('W', 'R');
+
+UPDATE enrollments e SET status = 'W', active = NULL
+WHERE e.active IS NOT NULL AND EXISTS (
+    SELECT 1 FROM enrollments n
+    WHERE n.user_id = e.user_id AND n.course_id = e.course_id AND n.active IS NOT NULL
+        AND (COALESCE(n.created_at, '0001-01-01') > COALESCE(e.created_at, '0001-01-01')
+            OR (COALESCE(n.created_at, '0001-01-01') = COALESCE(e.created_at, '0001-01-01') AND n.id > e.id))
+);
+
+CREATE UNIQUE INDEX idx_enrollment_user_course ON enrollments (user_id, course_id, active);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS refresh_tokens;
+
+ALTER TABLE users DROP COLUMN locale;
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users DROP COLUMN password;
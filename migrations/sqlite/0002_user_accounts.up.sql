@@ -0,0 +1,19 @@
+-- Contraseñas, roles, idioma y refresh tokens. Los usuarios que ya existían
+-- quedan como estudiantes y sin contraseña. SQLite no distingue CHAR de
+-- VARCHAR, así que las columnas de texto no cambian de tipo.
+ALTER TABLE users ADD COLUMN password VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'student';
+ALTER TABLE users ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT '';
+
+CREATE TABLE refresh_tokens (
+    id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    token_hash CHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL,
+    replaced_by CHAR(36) NULL,
+    created_at DATETIME NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens (user_id);
@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS course_instructors;
+DROP TABLE IF EXISTS course_prerequisites;
+
+ALTER TABLE courses DROP COLUMN enrollment_close;
+ALTER TABLE courses DROP COLUMN enrollment_open;
+ALTER TABLE courses DROP COLUMN capacity;
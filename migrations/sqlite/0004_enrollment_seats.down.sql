@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_enrollment_user_course;
+
+ALTER TABLE enrollments DROP COLUMN waitlist_position;
+ALTER TABLE enrollments DROP COLUMN active;
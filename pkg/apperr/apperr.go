@@ -0,0 +1,165 @@
+package apperr
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+type Kind string
+
+const (
+	KindValidation   Kind = "validation"
+	KindUnauthorized Kind = "unauthorized"
+	KindForbidden    Kind = "forbidden"
+	KindNotFound     Kind = "not_found"
+	KindConflict     Kind = "conflict"
+	KindTimeout      Kind = "timeout"
+	KindCanceled     Kind = "canceled"
+	KindInternal     Kind = "internal"
+)
+
+// StatusClientClosedRequest es el código no estándar (de nginx) para una
+// petición que el cliente abandonó antes de recibir la respuesta.
+const StatusClientClosedRequest = 499
+
+// Error es el error de dominio que devuelven servicios y repositorios.
+// Code es estable y pensado para que los clientes lo interpreten; Message
+// es el texto para mostrar y Details información adicional opcional.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Details interface{}
+	Err     error
+}
+
+func New(kind Kind, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func Validation(code, message string) *Error {
+	return New(KindValidation, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(KindUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(KindForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(KindNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(KindConflict, code, message)
+}
+
+func Timeout(code, message string) *Error {
+	return New(KindTimeout, code, message)
+}
+
+func Canceled(code, message string) *Error {
+	return New(KindCanceled, code, message)
+}
+
+// Internal envuelve un error inesperado; el mensaje original no se expone
+// al cliente pero queda disponible con errors.Unwrap.
+func Internal(err error) *Error {
+	return &Error{Kind: KindInternal, Code: "internal_error", Message: "internal server error", Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is permite comparar con errors.Is contra los errores declarados como
+// variables de paquete aunque se hayan copiado con WithDetails o Wrap.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+func (e *Error) WithMessage(message string) *Error {
+	c := *e
+	c.Message = message
+	return &c
+}
+
+func (e *Error) WithDetails(details interface{}) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+func (e *Error) Wrap(err error) *Error {
+	c := *e
+	c.Err = err
+	return &c
+}
+
+// From devuelve err como *Error; los errores desconocidos se tratan como internos.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Internal(err)
+}
+
+// DB traduce los errores de gorm: registro inexistente a notFound, clave
+// duplicada a conflicto, consulta que superó su plazo a timeout, consulta
+// cancelada porque el cliente se desconectó a canceled y cualquier otro a
+// error interno.
+func DB(err error, notFound *Error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return notFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return Conflict("duplicated", "resource already exists").Wrap(err)
+	case errors.Is(err, context.DeadlineExceeded):
+		return Timeout("query_timeout", "database query timed out").Wrap(err)
+	case errors.Is(err, context.Canceled):
+		return Canceled("request_canceled", "request was canceled").Wrap(err)
+	default:
+		var e *Error
+		if errors.As(err, &e) {
+			return e
+		}
+		return Internal(err)
+	}
+}
+
+func Status(err error) int {
+	switch From(err).Kind {
+	case KindValidation:
+		return http.StatusBadRequest
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindCanceled:
+		return StatusClientClosedRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,112 @@
+package apperr_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+)
+
+var errCourseNotFound = apperr.NotFound("course_not_found", "course does not exist")
+
+func TestDB(t *testing.T) {
+	errUnknown := errors.New("connection reset")
+	tests := []struct {
+		name string
+		err  error
+		kind apperr.Kind
+		code string
+	}{
+		{"record not found", gorm.ErrRecordNotFound, apperr.KindNotFound, "course_not_found"},
+		{"wrapped record not found", fmt.Errorf("find: %w", gorm.ErrRecordNotFound), apperr.KindNotFound, "course_not_found"},
+		{"duplicated key", gorm.ErrDuplicatedKey, apperr.KindConflict, "duplicated"},
+		{"deadline exceeded", context.DeadlineExceeded, apperr.KindTimeout, "query_timeout"},
+		{"canceled", context.Canceled, apperr.KindCanceled, "request_canceled"},
+		{"typed error", apperr.Conflict("course_full", "course is full"), apperr.KindConflict, "course_full"},
+		{"unknown error", errUnknown, apperr.KindInternal, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := apperr.From(apperr.DB(tt.err, errCourseNotFound))
+			if e.Kind != tt.kind || e.Code != tt.code {
+				t.Errorf("DB(%v) = %s/%s, want %s/%s", tt.err, e.Kind, e.Code, tt.kind, tt.code)
+			}
+			if tt.kind != apperr.KindNotFound && !errors.Is(e, tt.err) {
+				t.Errorf("DB(%v) = %v, does not wrap the original error", tt.err, e)
+			}
+		})
+	}
+
+	if err := apperr.DB(nil, errCourseNotFound); err != nil {
+		t.Errorf("DB(nil) = %v, want nil", err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{apperr.Validation("invalid", ""), http.StatusBadRequest},
+		{apperr.Unauthorized("invalid_token", ""), http.StatusUnauthorized},
+		{apperr.Forbidden("forbidden", ""), http.StatusForbidden},
+		{apperr.NotFound("course_not_found", ""), http.StatusNotFound},
+		{apperr.Conflict("duplicated", ""), http.StatusConflict},
+		{apperr.Timeout("query_timeout", ""), http.StatusGatewayTimeout},
+		{apperr.Canceled("request_canceled", ""), apperr.StatusClientClosedRequest},
+		{apperr.Internal(errors.New("boom")), http.StatusInternalServerError},
+		{errors.New("untyped"), http.StatusInternalServerError},
+		{fmt.Errorf("get: %w", apperr.NotFound("user_not_found", "")), http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if got := apperr.Status(tt.err); got != tt.want {
+			t.Errorf("Status(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	cause := errors.New("cause")
+	copies := map[string]*apperr.Error{
+		"WithMessage": errCourseNotFound.WithMessage("other message"),
+		"WithDetails": errCourseNotFound.WithDetails(map[string]string{"id": "go"}),
+		"Wrap":        errCourseNotFound.Wrap(cause),
+	}
+	for name, e := range copies {
+		if !errors.Is(e, errCourseNotFound) {
+			t.Errorf("%s copy is not errCourseNotFound", name)
+		}
+	}
+	if errors.Is(apperr.NotFound("user_not_found", ""), errCourseNotFound) {
+		t.Error("a different code matched errCourseNotFound")
+	}
+	if errors.Is(apperr.Conflict("course_not_found", ""), errCourseNotFound) {
+		t.Error("a different kind matched errCourseNotFound")
+	}
+
+	// Las copias no modifican el error del paquete.
+	if errCourseNotFound.Message != "course does not exist" || errCourseNotFound.Details != nil || errCourseNotFound.Err != nil {
+		t.Errorf("errCourseNotFound was modified: %+v", errCourseNotFound)
+	}
+	if wrapped := copies["Wrap"]; !errors.Is(wrapped, cause) || wrapped.Error() != "course does not exist: cause" {
+		t.Errorf("Wrap = %q, want the cause appended", wrapped.Error())
+	}
+}
+
+func TestFrom(t *testing.T) {
+	typed := apperr.Validation("invalid", "invalid")
+	if got := apperr.From(fmt.Errorf("context: %w", typed)); got != typed {
+		t.Errorf("From(wrapped) = %v, want the typed error", got)
+	}
+	cause := errors.New("boom")
+	got := apperr.From(cause)
+	if got.Kind != apperr.KindInternal || !errors.Is(got, cause) {
+		t.Errorf("From(untyped) = %+v, want an internal error wrapping it", got)
+	}
+	if got.Message != "internal server error" {
+		t.Errorf("From(untyped) message = %q, must not expose the cause", got.Message)
+	}
+}
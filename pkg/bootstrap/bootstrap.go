@@ -2,44 +2,193 @@ package bootstrap
 
 import (
 	"fmt"
-	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/glebarez/sqlite"
+	"github.com/raminpz/gocourse_web/internal/auth"
+	"github.com/raminpz/gocourse_web/migrations"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/logging"
+	"github.com/raminpz/gocourse_web/pkg/meta"
+	"github.com/raminpz/gocourse_web/pkg/metrics"
+	"github.com/raminpz/gocourse_web/pkg/migrate"
+	"github.com/raminpz/gocourse_web/pkg/response"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"log"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+	"log/slog"
 	"os"
+	"strings"
 )
 
-func DBConnection() (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local",
-		os.Getenv("DATABASE_USER"),
-		os.Getenv("DATABASE_PASSWORD"),
-		os.Getenv("DATABASE_HOST"),
-		os.Getenv("DATABASE_PORT"),
-		os.Getenv("DATABASE_NAME"))
+// DBConnection abre la base de datos y, si cfg.Migrate está activo, aplica las
+// migraciones pendientes antes de devolverla.
+func DBConnection(cfg config.Database, l *slog.Logger) (*gorm.DB, error) {
+	db, err := OpenDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Migrate {
+		migrator, err := Migrator(db, l)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := migrator.Up(); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+func OpenDB(cfg config.Database) (*gorm.DB, error) {
+	dialector, err := openDialector(cfg)
 	if err != nil {
 		return nil, err
 	}
-	if os.Getenv("DATABASE_DEBUG") == "true" {
+
+	db, err := gorm.Open(dialector, &gorm.Config{TranslateError: true})
+	if err != nil {
+		return nil, err
+	}
+	if err := metrics.InstrumentDB(db); err != nil {
+		return nil, err
+	}
+	if err := queryTimeout(db, cfg.QueryTimeout); err != nil {
+		return nil, err
+	}
+	// Un span por consulta, hijo del span de la petición que llega en el
+	// contexto. Los valores no se registran para no exportar datos personales.
+	if err := db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithoutQueryVariables())); err != nil {
+		return nil, err
+	}
+	if dialector.Name() == "sqlite" {
+		// SQLite admite un solo escritor a la vez; con una conexión las
+		// transacciones se serializan en lugar de fallar con "database is locked"
+		// y la base en memoria no se pierde al cerrarse una conexión.
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+		sqlDB.SetMaxOpenConns(1)
+	}
+	if cfg.Debug {
 		db = db.Debug()
 	}
+	return db, nil
+}
 
-	if os.Getenv("DATABASE_MIGRATE") == "true" {
-		if err := db.AutoMigrate(&domain.User{}); err != nil {
-			return nil, err
+// CloseDB cierra el pool de conexiones de db.
+func CloseDB(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Migrator devuelve el migrador con las migraciones embebidas del motor de db.
+// Sus consultas no tienen el plazo de DATABASE_QUERY_TIMEOUT.
+func Migrator(db *gorm.DB, l *slog.Logger) (*migrate.Migrator, error) {
+	return migrate.New(withoutQueryTimeout(db), migrations.FS, l)
+}
+
+// openDialector arma la conexión según cfg.Driver. cfg.DSN, si está definido,
+// reemplaza al DSN que se arma con el resto de los campos.
+func openDialector(cfg config.Database) (gorm.Dialector, error) {
+	dsn := cfg.DSN
+	switch strings.ToLower(cfg.Driver) {
+	case "", "mysql":
+		if dsn == "" {
+			dsn = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8&parseTime=True&loc=Local",
+				cfg.User,
+				cfg.Password,
+				cfg.Host,
+				cfg.Port,
+				cfg.Name)
+		}
+		return mysql.Open(dsn), nil
+	case "postgres", "postgresql":
+		if dsn == "" {
+			dsn = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+				cfg.Host,
+				cfg.Port,
+				cfg.User,
+				cfg.Password,
+				cfg.Name,
+				cfg.SSLMode)
 		}
+		return postgres.Open(dsn), nil
+	case "sqlite", "sqlite3":
+		if dsn == "" {
+			// Name es la ruta del archivo; vacío o ":memory:" usa una base en memoria.
+			name := cfg.Name
+			if name == "" || name == ":memory:" {
+				name = "file::memory:"
+			}
+			dsn = name + "?_pragma=foreign_keys(1)"
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported DATABASE_DRIVER %q: use mysql, postgres or sqlite", cfg.Driver)
+	}
+}
 
-		if err := db.AutoMigrate(&domain.Course{}); err != nil {
+// InitLoger arma los loggers de la aplicación, que escriben en stdout.
+func InitLoger(cfg config.Log) (*logging.Loggers, error) {
+	level, err := logging.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	levels, err := logging.ParseLevels(cfg.Levels)
+	if err != nil {
+		return nil, err
+	}
+	return logging.New(os.Stdout, cfg.Format, level, levels)
+}
+
+// InitSigner arma el firmador de JWT según cfg.Algorithm. Las llaves RS256 se
+// toman de PrivateKey/PublicKey o de los archivos PrivateKeyFile/PublicKeyFile.
+func InitSigner(cfg config.JWT) (*auth.Signer, error) {
+	switch strings.ToUpper(cfg.Algorithm) {
+	case "", "HS256":
+		return auth.NewHS256Signer([]byte(cfg.Secret), cfg.Issuer)
+	case "RS256":
+		privateKey, err := readKey(cfg.PrivateKey, cfg.PrivateKeyFile)
+		if err != nil {
 			return nil, err
 		}
-		if err := db.AutoMigrate(&domain.Enrollment{}); err != nil {
+		publicKey, err := readKey(cfg.PublicKey, cfg.PublicKeyFile)
+		if err != nil {
 			return nil, err
 		}
+		return auth.NewRS256Signer(privateKey, publicKey, cfg.Issuer)
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM: %s", cfg.Algorithm)
 	}
-	return db, nil
 }
 
-func InitLoger() *log.Logger {
-	return log.New(os.Stdout, "", log.LstdFlags|log.Lshortfile)
+// InitResponseVersion fija la versión de respuesta que reciben los clientes
+// que no envían el header API-Version.
+func InitResponseVersion(cfg config.API) error {
+	version, err := response.ParseVersion(cfg.DefaultVersion)
+	if err != nil {
+		return err
+	}
+	response.SetDefaultVersion(version)
+	return nil
+}
+
+// InitPaginator fija el tamaño de página de los listados sin límite.
+func InitPaginator(cfg config.Paginator) {
+	meta.SetDefaultPerPage(cfg.LimitPage)
+}
+
+func readKey(value, file string) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
 }
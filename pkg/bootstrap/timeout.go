@@ -0,0 +1,93 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	cancelKey = "timeout:cancel"
+	skipKey   = "timeout:skip"
+)
+
+type queryCancel struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	parent context.Context
+}
+
+// queryTimeout limita cada consulta de db a d, además de lo que limite el
+// contexto de la petición. Las sesiones marcadas con withoutQueryTimeout no
+// tienen plazo.
+func queryTimeout(db *gorm.DB, d time.Duration) error {
+	cb := db.Callback()
+	hooks := []struct {
+		operation     string
+		before, after func(name string, fn func(*gorm.DB)) error
+		// Las consultas row devuelven filas que se leen después del callback;
+		// su plazo no se cancela al terminar sino que vence solo.
+		rows bool
+	}{
+		{"create", cb.Create().Before("*").Register, cb.Create().After("*").Register, false},
+		{"query", cb.Query().Before("*").Register, cb.Query().After("*").Register, false},
+		{"update", cb.Update().Before("*").Register, cb.Update().After("*").Register, false},
+		{"delete", cb.Delete().Before("*").Register, cb.Delete().After("*").Register, false},
+		{"row", cb.Row().Before("*").Register, cb.Row().After("*").Register, true},
+		{"raw", cb.Raw().Before("*").Register, cb.Raw().After("*").Register, false},
+	}
+	for _, hook := range hooks {
+		if err := hook.before("timeout:before_"+hook.operation, withTimeout(d)); err != nil {
+			return err
+		}
+		if err := hook.after("timeout:after_"+hook.operation, releaseTimeout(!hook.rows)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func withTimeout(d time.Duration) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if _, ok := db.Get(skipKey); ok {
+			return
+		}
+		parent := db.Statement.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, d)
+		db.Statement.Context = ctx
+		db.InstanceSet(cancelKey, queryCancel{ctx: ctx, cancel: cancel, parent: parent})
+	}
+}
+
+// withoutQueryTimeout devuelve una sesión de db sin el plazo por consulta, para
+// tareas como las migraciones que pueden tardar más que una petición.
+func withoutQueryTimeout(db *gorm.DB) *gorm.DB {
+	return db.Set(skipKey, true).Session(&gorm.Session{})
+}
+
+// releaseTimeout devuelve el contexto original al statement por si se vuelve a
+// usar en otra consulta y, si cancel es true, libera el plazo. Cuando la
+// consulta falla con el contexto vencido el error envuelve al del contexto,
+// porque no todos los drivers lo devuelven (SQLite responde "interrupted").
+func releaseTimeout(cancel bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(cancelKey)
+		if !ok {
+			return
+		}
+		if qc, ok := value.(queryCancel); ok {
+			if err := qc.ctx.Err(); err != nil && db.Error != nil && !errors.Is(db.Error, err) {
+				db.Error = fmt.Errorf("%w: %v", err, db.Error)
+			}
+			if cancel {
+				qc.cancel()
+			}
+			db.Statement.Context = qc.parent
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package bootstrap
+
+import (
+	"context"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"gorm.io/gorm"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// slowQuery cuenta hasta cien millones; en SQLite tarda decenas de segundos.
+// Se ejecuta con Exec porque el driver solo interrumpe la ejecución de una
+// sentencia, no la lectura de sus filas.
+const slowQuery = `WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 100000000) SELECT count(*) FROM c`
+
+func openSQLite(t *testing.T, queryTimeout time.Duration) *gorm.DB {
+	t.Helper()
+	db, err := OpenDB(config.Database{Driver: "sqlite", QueryTimeout: queryTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { CloseDB(db) })
+	return db
+}
+
+func TestQueryTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		queryTimeout time.Duration
+		cancelAfter  time.Duration
+		wantKind     apperr.Kind
+		wantStatus   int
+	}{
+		{
+			name:         "query exceeds DATABASE_QUERY_TIMEOUT",
+			queryTimeout: 100 * time.Millisecond,
+			wantKind:     apperr.KindTimeout,
+			wantStatus:   http.StatusGatewayTimeout,
+		},
+		{
+			name:         "request context canceled mid-query",
+			queryTimeout: time.Minute,
+			cancelAfter:  100 * time.Millisecond,
+			wantKind:     apperr.KindCanceled,
+			wantStatus:   apperr.StatusClientClosedRequest,
+		},
+		{
+			name:         "request context canceled before the query",
+			queryTimeout: time.Minute,
+			wantKind:     apperr.KindCanceled,
+			wantStatus:   apperr.StatusClientClosedRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openSQLite(t, tt.queryTimeout)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			switch {
+			case tt.cancelAfter > 0:
+				time.AfterFunc(tt.cancelAfter, cancel)
+			case tt.wantKind == apperr.KindCanceled:
+				cancel()
+			}
+
+			start := time.Now()
+			err := apperr.DB(db.WithContext(ctx).Exec(slowQuery).Error, nil)
+			elapsed := time.Since(start)
+
+			if err == nil {
+				t.Fatal("slow query succeeded, want an error")
+			}
+			if got := apperr.From(err).Kind; got != tt.wantKind {
+				t.Errorf("kind = %s, want %s (%v)", got, tt.wantKind, err)
+			}
+			if got := apperr.Status(err); got != tt.wantStatus {
+				t.Errorf("status = %d, want %d", got, tt.wantStatus)
+			}
+			if elapsed > 2*time.Second {
+				t.Errorf("query returned after %s, want it interrupted", elapsed)
+			}
+		})
+	}
+}
+
+func TestWithoutQueryTimeout(t *testing.T) {
+	db := openSQLite(t, time.Nanosecond)
+
+	err := apperr.DB(db.Exec("SELECT 1").Error, nil)
+	if got := apperr.From(err).Kind; got != apperr.KindTimeout {
+		t.Fatalf("with a 1ns timeout: kind = %s (%v), want %s", got, err, apperr.KindTimeout)
+	}
+	if err := withoutQueryTimeout(db).Exec("SELECT 1").Error; err != nil {
+		t.Fatalf("without timeout: %v", err)
+	}
+}
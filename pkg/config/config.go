@@ -0,0 +1,464 @@
+package config
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"github.com/raminpz/gocourse_web/pkg/logging"
+	"github.com/raminpz/gocourse_web/pkg/response"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cada opción tiene una variable de entorno (tag env) y una clave en el archivo
+// de configuración (tag key de la sección y de la opción, p. ej. server.addr).
+// El tag default es su valor si no aparece en ningún lado y secret oculta el
+// valor al imprimir la configuración.
+type (
+	Config struct {
+		Server    Server    `key:"server"`
+		Storage   Storage   `key:"storage"`
+		Database  Database  `key:"database"`
+		JWT       JWT       `key:"jwt"`
+		API       API       `key:"api"`
+		Paginator Paginator `key:"paginator"`
+		Log       Log       `key:"log"`
+		Tracing   Tracing   `key:"tracing"`
+	}
+
+	Server struct {
+		Addr         string        `key:"addr" env:"SERVER_ADDR" default:"127.0.0.1:8000"`
+		ReadTimeout  time.Duration `key:"read_timeout" env:"SERVER_READ_TIMEOUT" default:"5s"`
+		WriteTimeout time.Duration `key:"write_timeout" env:"SERVER_WRITE_TIMEOUT" default:"5s"`
+
+		// ShutdownDelay es cuánto se sigue atendiendo con /readyz fallando antes
+		// de cerrar el listener; ShutdownTimeout, cuánto se espera después a las
+		// peticiones en curso.
+		ShutdownDelay   time.Duration `key:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY" default:"0s"`
+		ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" default:"15s"`
+	}
+
+	Storage struct {
+		// Type es database o memory; con memory los datos viven en el proceso,
+		// no se abre la base y se pierden al terminar.
+		Type string `key:"type" env:"STORAGE" default:"database"`
+	}
+
+	Database struct {
+		Driver       string        `key:"driver" env:"DATABASE_DRIVER" default:"mysql"`
+		DSN          string        `key:"dsn" env:"DATABASE_DSN" secret:"true"`
+		User         string        `key:"user" env:"DATABASE_USER"`
+		Password     string        `key:"password" env:"DATABASE_PASSWORD" secret:"true"`
+		Host         string        `key:"host" env:"DATABASE_HOST"`
+		Port         string        `key:"port" env:"DATABASE_PORT"`
+		Name         string        `key:"name" env:"DATABASE_NAME"`
+		SSLMode      string        `key:"sslmode" env:"DATABASE_SSLMODE" default:"disable"`
+		Debug        bool          `key:"debug" env:"DATABASE_DEBUG"`
+		Migrate      bool          `key:"migrate" env:"DATABASE_MIGRATE"`
+		QueryTimeout time.Duration `key:"query_timeout" env:"DATABASE_QUERY_TIMEOUT" default:"5s"`
+	}
+
+	JWT struct {
+		Algorithm      string        `key:"algorithm" env:"JWT_ALGORITHM" default:"HS256"`
+		Secret         string        `key:"secret" env:"JWT_SECRET" secret:"true"`
+		PrivateKey     string        `key:"private_key" env:"JWT_PRIVATE_KEY" secret:"true"`
+		PrivateKeyFile string        `key:"private_key_file" env:"JWT_PRIVATE_KEY_FILE"`
+		PublicKey      string        `key:"public_key" env:"JWT_PUBLIC_KEY"`
+		PublicKeyFile  string        `key:"public_key_file" env:"JWT_PUBLIC_KEY_FILE"`
+		Issuer         string        `key:"issuer" env:"JWT_ISSUER" default:"gocourse_web"`
+		AccessTTL      time.Duration `key:"access_ttl" env:"JWT_ACCESS_TTL" default:"15m"`
+		RefreshTTL     time.Duration `key:"refresh_ttl" env:"JWT_REFRESH_TTL" default:"720h"`
+	}
+
+	API struct {
+		DefaultVersion string `key:"default_version" env:"API_DEFAULT_VERSION" default:"2"`
+	}
+
+	Paginator struct {
+		LimitPage int `key:"limit_page" env:"PAGINATOR_LIMIT_PAGE" default:"10"`
+	}
+
+	Log struct {
+		Level  string `key:"level" env:"LOG_LEVEL" default:"info"`
+		Format string `key:"format" env:"LOG_FORMAT" default:"json"`
+
+		// Levels cambia el nivel de algunos paquetes, p. ej. "enrollment=debug,auth=warn".
+		Levels string `key:"levels" env:"LOG_LEVELS"`
+	}
+
+	Tracing struct {
+		// Exporter es none, stdout u otlp (OTLP sobre HTTP).
+		Exporter    string  `key:"exporter" env:"TRACING_EXPORTER" default:"none"`
+		Endpoint    string  `key:"endpoint" env:"TRACING_OTLP_ENDPOINT"`
+		Insecure    bool    `key:"insecure" env:"TRACING_OTLP_INSECURE"`
+		ServiceName string  `key:"service_name" env:"TRACING_SERVICE_NAME" default:"gocourse_web"`
+		SampleRatio float64 `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO" default:"1"`
+	}
+
+	// Errors son todos los problemas encontrados al cargar la configuración.
+	Errors []string
+
+	problem struct {
+		env string
+		msg string
+	}
+
+	option struct {
+		key    string
+		env    string
+		def    string
+		secret bool
+		value  reflect.Value
+	}
+)
+
+// FileEnv es la variable con la ruta del archivo de configuración opcional
+// (.yaml, .yml o .toml).
+const FileEnv = "CONFIG_FILE"
+
+const redacted = "[redacted]"
+
+func (e Errors) Error() string {
+	return "invalid configuration:\n  " + strings.Join(e, "\n  ")
+}
+
+// Load arma la configuración a partir de los valores por defecto, el archivo
+// indicado en CONFIG_FILE, el .env y las variables de entorno, en ese orden de
+// menor a mayor prioridad. Una variable vacía se considera no definida. Si algo
+// falta o es inválido devuelve Errors con todos los problemas juntos.
+//
+// sections limita la validación a esas secciones (p. ej. "database") para los
+// subcomandos que no usan el resto; sin sections se validan todas.
+func Load(sections ...string) (*Config, error) {
+	_ = godotenv.Load()
+
+	path := os.Getenv(FileEnv)
+	file, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	only, err := c.sections(sections)
+	if err != nil {
+		return nil, err
+	}
+	checked := func(key string) bool {
+		return len(only) == 0 || only[strings.SplitN(key, ".", 2)[0]]
+	}
+
+	var errs Errors
+	invalid := map[string]bool{}
+	for _, opt := range c.options() {
+		raw := opt.def
+		if value, ok := file[opt.key]; ok {
+			raw = value
+			delete(file, opt.key)
+		}
+		if value := os.Getenv(opt.env); value != "" {
+			raw = value
+		}
+		if err := set(opt.value, raw); err != nil && checked(opt.key) {
+			errs = append(errs, fmt.Sprintf("%s: invalid value %q: %v", opt.label(), raw, err))
+			invalid[opt.env] = true
+		}
+	}
+
+	unknown := make([]string, 0, len(file))
+	for key := range file {
+		unknown = append(unknown, key)
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		errs = append(errs, fmt.Sprintf("%s: unknown key %s", path, key))
+	}
+
+	// Las opciones que no se pudieron leer ya están en errs y no se revalidan.
+	for _, err := range c.validate(only) {
+		if opt, ok := c.option(err.env); ok && !checked(opt.key) {
+			continue
+		}
+		if !invalid[err.env] {
+			errs = append(errs, err.msg)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return c, nil
+}
+
+// Print escribe la configuración efectiva como VARIABLE=valor, con los
+// secretos ocultos.
+func (c *Config) Print(w io.Writer) {
+	for _, opt := range c.options() {
+		value := fmt.Sprint(opt.value.Interface())
+		if opt.secret && value != "" {
+			value = redacted
+		}
+		fmt.Fprintf(w, "%s=%s\n", opt.env, value)
+	}
+}
+
+// validate revisa las reglas entre opciones y sus valores permitidos. only son
+// las secciones pedidas a Load.
+func (c *Config) validate(only map[string]bool) []problem {
+	var problems []problem
+	fail := func(env, format string, args ...interface{}) {
+		problems = append(problems, problem{env: env, msg: c.label(env) + fmt.Sprintf(format, args...)})
+	}
+	required := func(env, value string) {
+		if strings.TrimSpace(value) == "" {
+			fail(env, " is required")
+		}
+	}
+	positive := func(env string, value time.Duration) {
+		if value <= 0 {
+			fail(env, " must be greater than zero")
+		}
+	}
+
+	required("SERVER_ADDR", c.Server.Addr)
+	positive("SERVER_READ_TIMEOUT", c.Server.ReadTimeout)
+	positive("SERVER_WRITE_TIMEOUT", c.Server.WriteTimeout)
+	positive("SERVER_SHUTDOWN_TIMEOUT", c.Server.ShutdownTimeout)
+
+	switch strings.ToLower(c.Storage.Type) {
+	case "database", "memory":
+	default:
+		fail("STORAGE", ": unsupported storage %q, use database or memory", c.Storage.Type)
+	}
+	// En memoria no se abre la base, así que sus opciones no se revisan salvo
+	// que se pida la sección, como hace migrate.
+	if strings.EqualFold(c.Storage.Type, "database") || only["database"] {
+		switch strings.ToLower(c.Database.Driver) {
+		case "mysql", "postgres", "postgresql":
+			// Sin DSN se arma uno con el resto de las variables DATABASE_*.
+			if c.Database.DSN == "" {
+				required("DATABASE_USER", c.Database.User)
+				required("DATABASE_HOST", c.Database.Host)
+				required("DATABASE_PORT", c.Database.Port)
+				required("DATABASE_NAME", c.Database.Name)
+			}
+		case "sqlite", "sqlite3":
+		default:
+			fail("DATABASE_DRIVER", ": unsupported driver %q, use mysql, postgres or sqlite", c.Database.Driver)
+		}
+		positive("DATABASE_QUERY_TIMEOUT", c.Database.QueryTimeout)
+	}
+
+	switch strings.ToUpper(c.JWT.Algorithm) {
+	case "HS256":
+		required("JWT_SECRET", c.JWT.Secret)
+		if c.JWT.Secret != "" && len(c.JWT.Secret) < 32 {
+			fail("JWT_SECRET", " must be at least 32 bytes")
+		}
+	case "RS256":
+		if c.JWT.PrivateKey == "" && c.JWT.PrivateKeyFile == "" {
+			fail("JWT_PRIVATE_KEY", " or %s is required", c.label("JWT_PRIVATE_KEY_FILE"))
+		}
+		if c.JWT.PublicKey == "" && c.JWT.PublicKeyFile == "" {
+			fail("JWT_PUBLIC_KEY", " or %s is required", c.label("JWT_PUBLIC_KEY_FILE"))
+		}
+	default:
+		fail("JWT_ALGORITHM", ": unsupported algorithm %q, use HS256 or RS256", c.JWT.Algorithm)
+	}
+	positive("JWT_ACCESS_TTL", c.JWT.AccessTTL)
+	positive("JWT_REFRESH_TTL", c.JWT.RefreshTTL)
+
+	if _, err := response.ParseVersion(c.API.DefaultVersion); err != nil {
+		fail("API_DEFAULT_VERSION", ": %v", err)
+	}
+	if c.Paginator.LimitPage <= 0 {
+		fail("PAGINATOR_LIMIT_PAGE", " must be greater than zero")
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		fail("LOG_LEVEL", ": %v", err)
+	}
+	switch strings.ToLower(c.Log.Format) {
+	case "json", "text":
+	default:
+		fail("LOG_FORMAT", ": unsupported format %q, use json or text", c.Log.Format)
+	}
+	if _, err := logging.ParseLevels(c.Log.Levels); err != nil {
+		fail("LOG_LEVELS", ": %v", err)
+	}
+
+	switch strings.ToLower(c.Tracing.Exporter) {
+	case "none", "stdout", "otlp":
+	default:
+		fail("TRACING_EXPORTER", ": unsupported exporter %q, use none, stdout or otlp", c.Tracing.Exporter)
+	}
+	required("TRACING_SERVICE_NAME", c.Tracing.ServiceName)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		fail("TRACING_SAMPLE_RATIO", " must be between 0 and 1")
+	}
+	return problems
+}
+
+// Memory indica si los datos se guardan en memoria en lugar de la base.
+func (s Storage) Memory() bool {
+	return strings.EqualFold(s.Type, "memory")
+}
+
+// label nombra una opción por su variable de entorno y su clave en el archivo.
+func (c *Config) label(env string) string {
+	if opt, ok := c.option(env); ok {
+		return opt.label()
+	}
+	return env
+}
+
+// option busca la opción de la variable env.
+func (c *Config) option(env string) (option, bool) {
+	for _, opt := range c.options() {
+		if opt.env == env {
+			return opt, true
+		}
+	}
+	return option{}, false
+}
+
+// sections arma el conjunto de secciones pedidas y rechaza las que no existen.
+func (c *Config) sections(names []string) (map[string]bool, error) {
+	known := map[string]bool{}
+	for _, opt := range c.options() {
+		known[strings.SplitN(opt.key, ".", 2)[0]] = true
+	}
+	only := map[string]bool{}
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("config: unknown section %q", name)
+		}
+		only[name] = true
+	}
+	return only, nil
+}
+
+func (o option) label() string {
+	return o.env + " (" + o.key + ")"
+}
+
+// options recorre las secciones de c y devuelve sus opciones en orden.
+func (c *Config) options() []option {
+	var opts []option
+	root := reflect.ValueOf(c).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i).Tag.Get("key")
+		sv := root.Field(i)
+		for j := 0; j < sv.NumField(); j++ {
+			sf := sv.Type().Field(j)
+			opts = append(opts, option{
+				key:    section + "." + sf.Tag.Get("key"),
+				env:    sf.Tag.Get("env"),
+				def:    sf.Tag.Get("default"),
+				secret: sf.Tag.Get("secret") == "true",
+				value:  sv.Field(j),
+			})
+		}
+	}
+	return opts
+}
+
+func set(v reflect.Value, raw string) error {
+	raw = strings.TrimSpace(raw)
+	switch v.Interface().(type) {
+	case string:
+		v.SetString(raw)
+	case time.Duration:
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case bool:
+		if raw == "" {
+			v.SetBool(false)
+			return nil
+		}
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case float64:
+		if raw == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case int:
+		if raw == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	default:
+		panic("config: unsupported type " + v.Type().String())
+	}
+	return nil
+}
+
+// readFile lee el archivo de configuración y lo aplana a claves
+// seccion.opcion con el valor como texto. Sin ruta devuelve un mapa vacío.
+func readFile(path string) (map[string]string, error) {
+	values := map[string]string{}
+	if path == "" {
+		return values, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tree map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("%s: unsupported config file format, use .yaml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	flatten("", tree, values)
+	return values, nil
+}
+
+func flatten(prefix string, tree map[string]interface{}, values map[string]string) {
+	for key, value := range tree {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flatten(key, value, values)
+		case nil:
+			values[key] = ""
+		default:
+			values[key] = fmt.Sprint(value)
+		}
+	}
+}
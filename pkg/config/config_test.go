@@ -0,0 +1,232 @@
+package config_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const secret = "test-secret-of-at-least-32-bytes!"
+
+// setEnv deja sin definir todas las variables de la configuración y después
+// define env, para que no influya el entorno de quien corre las pruebas.
+func setEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	var names bytes.Buffer
+	(&config.Config{}).Print(&names)
+	for _, line := range strings.Split(strings.TrimSpace(names.String()), "\n") {
+		name, _, _ := strings.Cut(line, "=")
+		t.Setenv(name, "")
+	}
+	t.Setenv(config.FileEnv, "")
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+}
+
+// writeFile escribe un archivo de configuración y lo indica en CONFIG_FILE.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(config.FileEnv, path)
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		file     string
+		content  string
+		sections []string
+		check    func(t *testing.T, c *config.Config)
+		// wantErrs son fragmentos de cada error esperado, en orden.
+		wantErrs []string
+	}{
+		{
+			name: "defaults",
+			env:  map[string]string{"STORAGE": "memory", "JWT_SECRET": secret},
+			check: func(t *testing.T, c *config.Config) {
+				if c.Server.Addr != "127.0.0.1:8000" || c.JWT.AccessTTL != 15*time.Minute || c.Paginator.LimitPage != 10 || c.Tracing.SampleRatio != 1 {
+					t.Errorf("config = %+v, want the defaults", c)
+				}
+			},
+		},
+		{
+			name:    "yaml file below the environment",
+			env:     map[string]string{"STORAGE": "memory", "JWT_SECRET": secret, "SERVER_ADDR": ":9000"},
+			file:    "config.yaml",
+			content: "server:\n  addr: \":8080\"\n  read_timeout: 2s\npaginator:\n  limit_page: 25\nlog:\n  levels:\n",
+			check: func(t *testing.T, c *config.Config) {
+				if c.Server.Addr != ":9000" || c.Server.ReadTimeout != 2*time.Second || c.Paginator.LimitPage != 25 {
+					t.Errorf("config = %+v, want addr from the environment and the rest from the file", c)
+				}
+			},
+		},
+		{
+			name:    "toml file",
+			env:     map[string]string{"JWT_SECRET": secret},
+			file:    "config.toml",
+			content: "[storage]\ntype = \"memory\"\n[database]\nmigrate = true\n[tracing]\nsample_ratio = 0.5\n",
+			check: func(t *testing.T, c *config.Config) {
+				if !c.Storage.Memory() || !c.Database.Migrate || c.Tracing.SampleRatio != 0.5 {
+					t.Errorf("config = %+v, want the values from the file", c)
+				}
+			},
+		},
+		{
+			name:     "unknown key in the file",
+			env:      map[string]string{"STORAGE": "memory", "JWT_SECRET": secret},
+			file:     "config.yaml",
+			content:  "server:\n  port: 80\n",
+			wantErrs: []string{"unknown key server.port"},
+		},
+		{
+			name: "every problem at once",
+			env: map[string]string{
+				"STORAGE":              "memory",
+				"JWT_SECRET":           "short",
+				"SERVER_READ_TIMEOUT":  "soon",
+				"PAGINATOR_LIMIT_PAGE": "0",
+				"LOG_FORMAT":           "xml",
+			},
+			wantErrs: []string{
+				`SERVER_READ_TIMEOUT (server.read_timeout): invalid value "soon"`,
+				"JWT_SECRET (jwt.secret) must be at least 32 bytes",
+				"PAGINATOR_LIMIT_PAGE (paginator.limit_page) must be greater than zero",
+				`LOG_FORMAT (log.format): unsupported format "xml"`,
+			},
+		},
+		{
+			name: "database options without a DSN",
+			env:  map[string]string{"JWT_SECRET": secret, "DATABASE_USER": "app"},
+			wantErrs: []string{
+				"DATABASE_HOST (database.host) is required",
+				"DATABASE_PORT (database.port) is required",
+				"DATABASE_NAME (database.name) is required",
+			},
+		},
+		{
+			name: "database options with a DSN",
+			env:  map[string]string{"JWT_SECRET": secret, "DATABASE_DRIVER": "postgres", "DATABASE_DSN": "postgres://app@db/app"},
+		},
+		{
+			name:     "unsupported driver",
+			env:      map[string]string{"JWT_SECRET": secret, "DATABASE_DRIVER": "oracle"},
+			wantErrs: []string{`DATABASE_DRIVER (database.driver): unsupported driver "oracle"`},
+		},
+		{
+			name: "RS256 without keys",
+			env:  map[string]string{"STORAGE": "memory", "JWT_ALGORITHM": "RS256"},
+			wantErrs: []string{
+				"JWT_PRIVATE_KEY (jwt.private_key) or JWT_PRIVATE_KEY_FILE (jwt.private_key_file) is required",
+				"JWT_PUBLIC_KEY (jwt.public_key) or JWT_PUBLIC_KEY_FILE (jwt.public_key_file) is required",
+			},
+		},
+		{
+			name:     "only the requested sections",
+			env:      map[string]string{"STORAGE": "memory", "DATABASE_DRIVER": "sqlite", "LOG_LEVEL": "loud", "SERVER_WRITE_TIMEOUT": "never"},
+			sections: []string{"database"},
+			check: func(t *testing.T, c *config.Config) {
+				if c.Database.Driver != "sqlite" {
+					t.Errorf("driver = %q, want sqlite", c.Database.Driver)
+				}
+			},
+		},
+		{
+			name:     "database section is checked even in memory",
+			env:      map[string]string{"STORAGE": "memory", "DATABASE_DRIVER": "oracle"},
+			sections: []string{"database"},
+			wantErrs: []string{`DATABASE_DRIVER (database.driver): unsupported driver "oracle"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, tt.env)
+			if tt.file != "" {
+				writeFile(t, tt.file, tt.content)
+			}
+			c, err := config.Load(tt.sections...)
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Fatalf("Load error = %v", err)
+				}
+				if tt.check != nil {
+					tt.check(t, c)
+				}
+				return
+			}
+
+			var errs config.Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Load error = %v, want config.Errors", err)
+			}
+			if len(errs) != len(tt.wantErrs) {
+				t.Fatalf("Load errors = %q, want %d", errs, len(tt.wantErrs))
+			}
+			for i, want := range tt.wantErrs {
+				if !strings.Contains(errs[i], want) {
+					t.Errorf("error %d = %q, want it to contain %q", i, errs[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{name: "unsupported format", file: "config.json", content: "{}", want: "unsupported config file format"},
+		{name: "invalid yaml", file: "config.yaml", content: "server: [", want: "config.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setEnv(t, nil)
+			writeFile(t, tt.file, tt.content)
+			if _, err := config.Load(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		setEnv(t, map[string]string{config.FileEnv: filepath.Join(t.TempDir(), "missing.yaml")})
+		if _, err := config.Load(); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("Load error = %v, want os.ErrNotExist", err)
+		}
+	})
+
+	t.Run("unknown section", func(t *testing.T) {
+		setEnv(t, nil)
+		if _, err := config.Load("cache"); err == nil || !strings.Contains(err.Error(), `unknown section "cache"`) {
+			t.Errorf("Load error = %v, want unknown section", err)
+		}
+	})
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	setEnv(t, map[string]string{"STORAGE": "memory", "JWT_SECRET": secret, "DATABASE_PASSWORD": "hunter2", "DATABASE_USER": "app"})
+	c, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	c.Print(&out)
+	printed := out.String()
+	for _, want := range []string{"JWT_SECRET=[redacted]\n", "DATABASE_PASSWORD=[redacted]\n", "DATABASE_USER=app\n", "DATABASE_DSN=\n"} {
+		if !strings.Contains(printed, want) {
+			t.Errorf("Print output is missing %q:\n%s", want, printed)
+		}
+	}
+	if strings.Contains(printed, secret) || strings.Contains(printed, "hunter2") {
+		t.Errorf("Print leaked a secret:\n%s", printed)
+	}
+}
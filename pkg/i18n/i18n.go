@@ -0,0 +1,121 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ES = "es"
+	EN = "en"
+)
+
+// Default es el idioma que se usa cuando el cliente no pide ninguno soportado.
+var Default = EN
+
+//go:embed locales/*.json
+var files embed.FS
+
+// catalog guarda los mensajes por idioma y clave. Las claves de los errores
+// son sus códigos, así el código se mantiene estable en cualquier idioma.
+var catalog = map[string]map[string]string{}
+
+func init() {
+	entries, err := files.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		data, err := files.ReadFile("locales/" + entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic("i18n: invalid catalog " + entry.Name() + ": " + err.Error())
+		}
+		catalog[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+}
+
+type ctxKey struct{}
+
+// Localizable lo implementan los detalles de un error que traen sus propios
+// mensajes, como los errores de validación por campo.
+type Localizable interface {
+	Localize(lang string) interface{}
+}
+
+// Supported indica si hay un catálogo para lang.
+func Supported(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// Message devuelve el mensaje key en lang reemplazando cada {nombre} por su
+// valor en args. Si lang no tiene la clave se usa el idioma por defecto.
+func Message(lang, key string, args map[string]string) (string, bool) {
+	msg, ok := catalog[lang][key]
+	if !ok {
+		msg, ok = catalog[Default][key]
+	}
+	if !ok {
+		return "", false
+	}
+	for name, value := range args {
+		msg = strings.ReplaceAll(msg, "{"+name+"}", value)
+	}
+	return msg, true
+}
+
+// WithLocale guarda en ctx el idioma preferido del usuario autenticado.
+func WithLocale(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, lang)
+}
+
+// FromRequest elige el idioma de la respuesta: primero la preferencia del
+// usuario, luego el header Accept-Language y por último el idioma por defecto.
+func FromRequest(r *http.Request) string {
+	if lang, ok := r.Context().Value(ctxKey{}).(string); ok && Supported(lang) {
+		return lang
+	}
+	if lang := Negotiate(r.Header.Get("Accept-Language")); lang != "" {
+		return lang
+	}
+	return Default
+}
+
+// Negotiate devuelve el idioma soportado con mayor peso en un header
+// Accept-Language, o "" si ninguno lo está.
+func Negotiate(header string) string {
+	type option struct {
+		lang string
+		q    float64
+	}
+	var options []option
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if q > 0 && Supported(base) {
+			options = append(options, option{lang: base, q: q})
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool { return options[i].q > options[j].q })
+	if len(options) == 0 {
+		return ""
+	}
+	return options[0].lang
+}
@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type (
+	// Loggers arma el logger de cada paquete con el nivel configurado para él.
+	Loggers struct {
+		base   slog.Handler
+		level  slog.Level
+		levels map[string]slog.Level
+	}
+
+	// handler aplica el nivel del logger y agrega el request_id y la traza del
+	// contexto a cada registro, de modo que todo lo que se registre con los
+	// métodos *Context durante una petición quede asociado a ella.
+	handler struct {
+		slog.Handler
+		level slog.Level
+	}
+)
+
+// New crea los loggers que escriben en w con formato "json" o "text". level es
+// el nivel por defecto y levels el de cada paquete que lo cambie.
+func New(w io.Writer, format string, level slog.Level, levels map[string]slog.Level) (*Loggers, error) {
+	// El nivel lo decide cada handler; el base deja pasar todo.
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var base slog.Handler
+	switch strings.ToLower(format) {
+	case "", "json":
+		base = slog.NewJSONHandler(w, opts)
+	case "text":
+		base = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unsupported log format %q: use json or text", format)
+	}
+	return &Loggers{base: base, level: level, levels: levels}, nil
+}
+
+// Root devuelve el logger general, con el nivel por defecto.
+func (l *Loggers) Root() *slog.Logger {
+	return slog.New(handler{Handler: l.base, level: l.level})
+}
+
+// For devuelve el logger del paquete name, que se agrega a cada registro.
+func (l *Loggers) For(name string) *slog.Logger {
+	level, ok := l.levels[name]
+	if !ok {
+		level = l.level
+	}
+	return slog.New(handler{Handler: l.base, level: level}).With("package", name)
+}
+
+func (h handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h handler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return handler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h handler) WithGroup(name string) slog.Handler {
+	return handler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// ParseLevel acepta debug, info, warn o error.
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(strings.TrimSpace(s)))
+	return level, err
+}
+
+// ParseLevels lee niveles por paquete con la forma "enrollment=debug,auth=warn".
+func ParseLevels(s string) (map[string]slog.Level, error) {
+	levels := map[string]slog.Level{}
+	for _, item := range strings.Split(s, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid package level %q: use package=level", item)
+		}
+		level, err := ParseLevel(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid level for %s: %w", name, err)
+		}
+		levels[name] = level
+	}
+	return levels, nil
+}
@@ -1,10 +1,5 @@
 package meta
 
-import (
-	"os"
-	"strconv"
-)
-
 type Meta struct {
 	TotalCount int `json:"total_count"`
 	PagesCount int `json:"pages_count"`
@@ -12,14 +7,17 @@ type Meta struct {
 	PerPage    int `json:"per_page"`
 }
 
+var defaultPerPage = 10
+
+// SetDefaultPerPage fija cuántos elementos por página se usan cuando el
+// cliente no indica un límite.
+func SetDefaultPerPage(n int) {
+	defaultPerPage = n
+}
+
 func New(page, perPage, total int) (*Meta, error) {
 	if perPage <= 0 {
-		var err error
-		perPage, err = strconv.Atoi(os.Getenv("PAGINATOR_LIMIT_PAGE"))
-		if err != nil {
-			return nil, err
-		}
-
+		perPage = defaultPerPage
 	}
 
 	pageCount := 0
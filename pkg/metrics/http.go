@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// unmatched es la ruta con la que se registran las peticiones que no
+// corresponden a ninguna ruta, para no crear una serie por cada URL.
+const unmatched = "unmatched"
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// Middleware mide las peticiones que atiende router etiquetándolas con la
+// plantilla de la ruta (p. ej. /users/{id}) en lugar de la URL. Debe envolver
+// al router para medir también las peticiones que no coinciden con una ruta.
+func Middleware(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := unmatched
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			if tpl, err := match.Route.GetPathTemplate(); err == nil {
+				route = tpl
+			}
+		}
+
+		inFlight := httpInFlight.WithLabelValues(r.Method, route)
+		inFlight.Inc()
+		defer inFlight.Dec()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		router.ServeHTTP(rec, r)
+
+		status := strconv.Itoa(rec.status)
+		httpRequests.WithLabelValues(r.Method, route, status).Inc()
+		httpDuration.WithLabelValues(r.Method, route, status).Observe(time.Since(start).Seconds())
+	})
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
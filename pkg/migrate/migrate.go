@@ -0,0 +1,387 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	Migration struct {
+		Version int64
+		Name    string
+		Up      string
+		Down    string
+	}
+
+	// Status es una migración junto con la fecha en que se aplicó, o nil si
+	// está pendiente.
+	Status struct {
+		Migration
+		AppliedAt *time.Time
+	}
+
+	Migrator struct {
+		db         *gorm.DB
+		log        *slog.Logger
+		dialect    string
+		migrations []Migration
+	}
+
+	record struct {
+		Version   int64
+		Name      string
+		AppliedAt time.Time
+	}
+)
+
+var ErrLocked = errors.New("another process is running migrations")
+
+// LockTimeout es cuánto espera una instancia a que otra termine de migrar.
+var LockTimeout = time.Minute
+
+// Dialects son los directorios de migraciones, uno por motor soportado.
+var Dialects = []string{"mysql", "postgres", "sqlite"}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+const lockName = "gocourse_web_schema_migrations"
+
+// New carga las migraciones del motor de db desde el directorio con su nombre
+// dentro de fsys.
+func New(db *gorm.DB, fsys fs.FS, logger *slog.Logger) (*Migrator, error) {
+	dialect := db.Dialector.Name()
+	migrations, err := load(fsys, dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		db:         db,
+		log:        logger,
+		dialect:    dialect,
+		migrations: migrations,
+	}, nil
+}
+
+func load(fsys fs.FS, dialect string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dialect)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for %s: %w", dialect, err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		data, err := fs.ReadFile(fsys, path.Join(dialect, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Status devuelve todas las migraciones conocidas indicando cuáles se aplicaron.
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			s := Status{Migration: migration}
+			if r, ok := applied[migration.Version]; ok {
+				appliedAt := r.AppliedAt
+				s.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Version devuelve la última versión aplicada y la última que conoce el
+// migrador. No toma el lock, así que sirve para consultas frecuentes como la
+// del health check.
+func (m *Migrator) Version(ctx context.Context) (applied, latest int64, err error) {
+	if len(m.migrations) > 0 {
+		latest = m.migrations[len(m.migrations)-1].Version
+	}
+	var version sql.NullInt64
+	err = m.db.WithContext(ctx).Raw("SELECT MAX(version) FROM schema_migrations").Scan(&version).Error
+	return version.Int64, latest, err
+}
+
+// Up aplica en orden todas las migraciones pendientes y devuelve cuántas aplicó.
+func (m *Migrator) Up() (int, error) {
+	count := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(conn, migration, true); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down revierte las últimas n migraciones aplicadas y devuelve cuántas revirtió.
+func (m *Migrator) Down(n int) (int, error) {
+	count := 0
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := m.applied(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && count < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if err := m.run(conn, migration, false); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// run ejecuta el script de la migración y registra (o borra) su versión en la
+// misma transacción. En MySQL las sentencias DDL hacen commit implícito, así
+// que una migración que falla a la mitad puede quedar aplicada en parte.
+func (m *Migrator) run(conn *gorm.DB, migration Migration, up bool) error {
+	direction, script := "down", migration.Down
+	if up {
+		direction, script = "up", migration.Up
+	}
+	m.log.Info("migrating", "direction", direction, "version", migration.Version, "name", migration.Name)
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		if up {
+			return tx.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+				migration.Version, migration.Name, time.Now().UTC()).Error
+		}
+		return tx.Exec("DELETE FROM schema_migrations WHERE version = ?", migration.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s %s: %w", migration.Version, migration.Name, direction, err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(conn *gorm.DB) (map[int64]record, error) {
+	var records []record
+	if err := conn.Raw("SELECT version, name, applied_at FROM schema_migrations").Scan(&records).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]record, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return applied, nil
+}
+
+// withLock ejecuta fn sobre una sola conexión mientras tiene el lock de
+// migraciones, para que dos instancias no migren a la vez.
+func (m *Migrator) withLock(fn func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		unlock, err := m.lock(conn)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		if err := conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (" +
+			"version BIGINT NOT NULL PRIMARY KEY, " +
+			"name VARCHAR(255) NOT NULL, " +
+			"applied_at " + m.timestampType() + " NOT NULL)").Error; err != nil {
+			return err
+		}
+		return fn(conn)
+	})
+}
+
+// lock toma el lock de migraciones. MySQL y PostgreSQL usan un lock de sesión,
+// que la base libera aunque el proceso muera sin soltarlo; SQLite no tiene uno,
+// así que se inserta una fila en schema_migrations_lock, lo que requiere que la
+// conexión traduzca los errores de clave duplicada (gorm.Config.TranslateError).
+func (m *Migrator) lock(conn *gorm.DB) (func(), error) {
+	switch m.dialect {
+	case "mysql":
+		var acquired sql.NullInt64
+		if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, int(LockTimeout.Seconds())).Scan(&acquired).Error; err != nil {
+			return nil, err
+		}
+		if !acquired.Valid || acquired.Int64 != 1 {
+			return nil, ErrLocked
+		}
+		return func() { conn.Exec("SELECT RELEASE_LOCK(?)", lockName) }, nil
+	case "postgres":
+		// pg_advisory_lock espera sin límite; el tiempo máximo lo fija lock_timeout.
+		if err := conn.Exec(fmt.Sprintf("SET lock_timeout = %d", LockTimeout.Milliseconds())).Error; err != nil {
+			return nil, err
+		}
+		if err := conn.Exec("SELECT pg_advisory_lock(hashtext(?))", lockName).Error; err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrLocked, err)
+		}
+		return func() {
+			conn.Exec("SELECT pg_advisory_unlock(hashtext(?))", lockName)
+			conn.Exec("RESET lock_timeout")
+		}, nil
+	default:
+		if err := conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations_lock (" +
+			"id INTEGER NOT NULL PRIMARY KEY, " +
+			"locked_at " + m.timestampType() + " NOT NULL)").Error; err != nil {
+			return nil, err
+		}
+		// Los intentos fallidos son esperables; no se registran como errores.
+		quiet := conn.Session(&gorm.Session{Logger: conn.Logger.LogMode(gormlogger.Silent)})
+		deadline := time.Now().Add(LockTimeout)
+		for {
+			err := quiet.Exec("INSERT INTO schema_migrations_lock (id, locked_at) VALUES (1, ?)", time.Now().UTC()).Error
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, gorm.ErrDuplicatedKey) {
+				return nil, err
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("%w: delete the row in schema_migrations_lock if no other process is migrating", ErrLocked)
+			}
+			time.Sleep(time.Second)
+		}
+		return func() { conn.Exec("DELETE FROM schema_migrations_lock WHERE id = 1") }, nil
+	}
+}
+
+func (m *Migrator) timestampType() string {
+	if m.dialect == "mysql" {
+		return "DATETIME(3)"
+	}
+	return "TIMESTAMP"
+}
+
+// statements separa un script en sentencias. Cada sentencia debe terminar con
+// ";" al final de una línea; las líneas que empiezan con "--" se ignoran.
+func statements(script string) []string {
+	var (
+		result  []string
+		current strings.Builder
+	)
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			result = append(result, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		result = append(result, rest)
+	}
+	return result
+}
+
+// Create agrega el par de archivos up/down de una nueva migración en el
+// directorio de cada motor dentro de dir y devuelve las rutas creadas.
+func Create(dir, name string) ([]string, error) {
+	name = regexp.MustCompile(`\W+`).ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	version, err := nextVersion(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var created []string
+	for _, dialect := range Dialects {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dir, dialect, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+			content := fmt.Sprintf("-- %04d_%s %s (%s)\n", version, name, direction, dialect)
+			if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+				return created, err
+			}
+			created = append(created, file)
+		}
+	}
+	return created, nil
+}
+
+func nextVersion(dir string) (int64, error) {
+	var last int64
+	for _, dialect := range Dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if err != nil {
+			return 0, err
+		}
+		for _, entry := range entries {
+			if match := fileName.FindStringSubmatch(entry.Name()); match != nil {
+				version, _ := strconv.ParseInt(match[1], 10, 64)
+				if version > last {
+					last = version
+				}
+			}
+		}
+	}
+	return last + 1, nil
+}
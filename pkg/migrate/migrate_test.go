@@ -0,0 +1,205 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// openSQLite abre una base SQLite en un archivo temporal, para que cada
+// conexión del pool vea los mismos datos.
+func openSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{TranslateError: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+func newMigrator(t *testing.T, db *gorm.DB, files fstest.MapFS) *Migrator {
+	t.Helper()
+	m, err := New(db, files, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// scripts son dos migraciones que crean las tablas a y b.
+var scripts = fstest.MapFS{
+	"sqlite/0001_create_a.up.sql":   {Data: []byte("-- tabla a\nCREATE TABLE a (id INTEGER);\n")},
+	"sqlite/0001_create_a.down.sql": {Data: []byte("DROP TABLE a;\n")},
+	"sqlite/0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);\nINSERT INTO b VALUES (1);\n")},
+	"sqlite/0002_create_b.down.sql": {Data: []byte("DROP TABLE b;\n")},
+	"sqlite/README.md":              {Data: []byte("no es una migración")},
+}
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		name, script string
+		want         []string
+	}{
+		{name: "empty", script: "\n  \n"},
+		{name: "one per line", script: "CREATE TABLE a (id INT);\nDROP TABLE b;\n", want: []string{"CREATE TABLE a (id INT);", "DROP TABLE b;"}},
+		{name: "comments and blank lines", script: "-- crea a\n\nCREATE TABLE a (id INT);\n  -- fin\n", want: []string{"CREATE TABLE a (id INT);"}},
+		{name: "several lines", script: "CREATE TABLE a (\n    id INT\n);\n", want: []string{"CREATE TABLE a (\n    id INT\n);"}},
+		{name: "semicolon inside a line", script: "SELECT ';' AS x, 1\nFROM t;\n", want: []string{"SELECT ';' AS x, 1\nFROM t;"}},
+		{name: "without a final semicolon", script: "DROP TABLE a;\nDROP TABLE b", want: []string{"DROP TABLE a;", "DROP TABLE b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := statements(tt.script); fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.want) {
+				t.Errorf("statements = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := load(scripts, "sqlite")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[1].Name != "create_b" || !strings.Contains(migrations[1].Down, "DROP TABLE b") {
+		t.Errorf("load = %+v, want create_a and create_b in order", migrations)
+	}
+
+	if _, err := load(scripts, "mysql"); err == nil {
+		t.Error("load without a mysql directory succeeded")
+	}
+
+	conflict := fstest.MapFS{
+		"sqlite/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"sqlite/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+	}
+	if _, err := load(conflict, "sqlite"); err == nil || !strings.Contains(err.Error(), "two names") {
+		t.Errorf("load error = %v, want two names", err)
+	}
+}
+
+func TestUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	m := newMigrator(t, db, scripts)
+
+	version := func(want int64) {
+		t.Helper()
+		applied, latest, err := m.Version(ctx)
+		if err != nil || applied != want || latest != 2 {
+			t.Fatalf("Version = %d, %d, %v; want %d, 2", applied, latest, err, want)
+		}
+	}
+
+	if n, err := m.Up(); err != nil || n != 2 {
+		t.Fatalf("Up = %d, %v; want 2", n, err)
+	}
+	version(2)
+	if n, err := m.Up(); err != nil || n != 0 {
+		t.Fatalf("second Up = %d, %v; want 0", n, err)
+	}
+
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %d is not applied", s.Version)
+		}
+	}
+
+	if n, err := m.Down(1); err != nil || n != 1 {
+		t.Fatalf("Down(1) = %d, %v; want 1", n, err)
+	}
+	version(1)
+	if db.Migrator().HasTable("b") || !db.Migrator().HasTable("a") {
+		t.Error("Down(1) did not drop only b")
+	}
+	if n, err := m.Down(5); err != nil || n != 1 {
+		t.Fatalf("Down(5) = %d, %v; want 1", n, err)
+	}
+	version(0)
+}
+
+func TestFailedMigrationIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	files := fstest.MapFS{
+		"sqlite/0001_ok.up.sql":     {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"sqlite/0002_broken.up.sql": {Data: []byte("CREATE TABLE b (id INTEGER);\nINSERT INTO missing VALUES (1);\n")},
+	}
+	m := newMigrator(t, db, files)
+
+	n, err := m.Up()
+	if err == nil || !strings.Contains(err.Error(), "0002_broken up") || n != 1 {
+		t.Fatalf("Up = %d, %v; want 1 and an error in 0002_broken", n, err)
+	}
+	if applied, _, _ := m.Version(ctx); applied != 1 {
+		t.Errorf("applied version = %d, want 1", applied)
+	}
+	if db.Migrator().HasTable("b") {
+		t.Error("the broken migration left table b behind")
+	}
+}
+
+func TestLock(t *testing.T) {
+	defer func(timeout time.Duration) { LockTimeout = timeout }(LockTimeout)
+	LockTimeout = 0
+
+	db := openSQLite(t)
+	first, second := newMigrator(t, db, scripts), newMigrator(t, db, scripts)
+	err := first.withLock(func(*gorm.DB) error {
+		_, err := second.Up()
+		return err
+	})
+	if !errors.Is(err, ErrLocked) {
+		t.Fatalf("Up while locked error = %v, want ErrLocked", err)
+	}
+	if n, err := second.Up(); err != nil || n != 2 {
+		t.Errorf("Up after the lock was released = %d, %v; want 2", n, err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	for _, dialect := range Dialects {
+		if err := os.Mkdir(filepath.Join(dir, dialect), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "postgres", "0007_old.up.sql"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Create(dir, "  Add course index! ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2*len(Dialects) {
+		t.Fatalf("Create = %v, want up and down for each dialect", files)
+	}
+	if want := filepath.Join(dir, "mysql", "0008_add_course_index.up.sql"); files[0] != want {
+		t.Errorf("first file = %s, want %s", files[0], want)
+	}
+
+	if _, err := Create(dir, " !! "); err == nil {
+		t.Error("Create without a name succeeded")
+	}
+}
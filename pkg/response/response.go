@@ -0,0 +1,133 @@
+package response
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/i18n"
+	"github.com/raminpz/gocourse_web/pkg/meta"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Version identifica el formato de las respuestas. V1 conserva la forma que
+// cada endpoint tenía antes de unificar el envelope para que los clientes
+// viejos sigan funcionando mientras migran.
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+)
+
+// VersionHeader es el header con el que cada cliente elige la versión; sin él
+// se usa la versión por defecto.
+const VersionHeader = "API-Version"
+
+var defaultVersion = V2
+
+type Response struct {
+	Status  int         `json:"status"`
+	Data    interface{} `json:"data,omitempty"`
+	Err     string      `json:"error,omitempty"`
+	Code    string      `json:"code,omitempty"`
+	Details interface{} `json:"details,omitempty"`
+	Meta    *meta.Meta  `json:"meta,omitempty"`
+}
+
+// legacyResponse es el envelope de V1: el mismo de Response sin code ni
+// details, que los clientes viejos no conocen.
+type legacyResponse struct {
+	Status int         `json:"status"`
+	Data   interface{} `json:"data,omitempty"`
+	Err    string      `json:"error,omitempty"`
+	Meta   *meta.Meta  `json:"meta,omitempty"`
+}
+
+func SetDefaultVersion(v Version) {
+	defaultVersion = v
+}
+
+func ParseVersion(s string) (Version, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "v") {
+	case "1":
+		return V1, nil
+	case "2":
+		return V2, nil
+	default:
+		return 0, fmt.Errorf("invalid api version: %q", s)
+	}
+}
+
+// VersionOf devuelve la versión pedida en r o la versión por defecto.
+func VersionOf(r *http.Request) Version {
+	if v, err := ParseVersion(r.Header.Get(VersionHeader)); err == nil {
+		return v
+	}
+	return defaultVersion
+}
+
+func OK(data interface{}) *Response {
+	return &Response{Status: http.StatusOK, Data: data}
+}
+
+func Page(data interface{}, m *meta.Meta) *Response {
+	return &Response{Status: http.StatusOK, Data: data, Meta: m}
+}
+
+// FromError arma la respuesta de err con el status y código que le
+// corresponden según su tipo; los errores no tipados se responden como 500.
+func FromError(err error) *Response {
+	e := apperr.From(err)
+	return &Response{Status: apperr.Status(e), Err: e.Message, Code: e.Code, Details: e.Details}
+}
+
+// Write escribe res como JSON con los mensajes de error en el idioma del
+// cliente. Si el cliente pidió V1 y legacy no es nil se escribe lo que devuelva
+// legacy en su lugar, manteniendo el status de res.
+func Write(w http.ResponseWriter, r *http.Request, res *Response, legacy func(*Response) interface{}) {
+	version := VersionOf(r)
+	lang := i18n.FromRequest(r)
+	res = localize(res, lang)
+
+	var body interface{} = res
+	if version == V1 && legacy != nil {
+		body = legacy(res)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Language", lang)
+	w.Header().Set(VersionHeader, strconv.Itoa(int(version)))
+	w.WriteHeader(res.Status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Legacy es el adaptador V1 por defecto, el que usan JSON y Error.
+func Legacy(res *Response) interface{} {
+	return legacyResponse{Status: res.Status, Data: res.Data, Err: res.Err, Meta: res.Meta}
+}
+
+func JSON(w http.ResponseWriter, r *http.Request, res *Response) {
+	Write(w, r, res, Legacy)
+}
+
+func Error(w http.ResponseWriter, r *http.Request, err error) {
+	JSON(w, r, FromError(err))
+}
+
+// localize traduce el mensaje de error según su código; los detalles de tipo
+// map[string]string se usan como parámetros del mensaje.
+func localize(res *Response, lang string) *Response {
+	if res.Code == "" {
+		return res
+	}
+	localized := *res
+	args, _ := res.Details.(map[string]string)
+	if msg, ok := i18n.Message(lang, res.Code, args); ok {
+		localized.Err = msg
+	}
+	if details, ok := res.Details.(i18n.Localizable); ok {
+		localized.Details = details.Localize(lang)
+	}
+	return &localized
+}
@@ -0,0 +1,215 @@
+package response_test
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/meta"
+	"github.com/raminpz/gocourse_web/pkg/response"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fieldErrors es un detalle que trae sus propios mensajes.
+type fieldErrors []string
+
+func (f fieldErrors) Localize(lang string) interface{} {
+	out := make([]string, len(f))
+	for i, field := range f {
+		out[i] = lang + ":" + field
+	}
+	return out
+}
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    response.Version
+		wantErr bool
+	}{
+		{"1", response.V1, false},
+		{"v1", response.V1, false},
+		{" V2 ", response.V2, false},
+		{"2", response.V2, false},
+		{"", 0, true},
+		{"3", 0, true},
+		{"latest", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := response.ParseVersion(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseVersion(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers map[string]string
+		res     *response.Response
+		status  int
+		version string
+		lang    string
+		want    string
+	}{
+		{
+			name:    "ok in v2 by default",
+			res:     response.OK(map[string]string{"id": "go"}),
+			status:  http.StatusOK,
+			version: "2",
+			lang:    "en",
+			want:    `{"status":200,"data":{"id":"go"}}`,
+		},
+		{
+			name:    "page keeps meta",
+			res:     response.Page([]string{"go"}, &meta.Meta{TotalCount: 1}),
+			status:  http.StatusOK,
+			version: "2",
+			lang:    "en",
+			want:    `{"status":200,"data":["go"],"meta":{"total_count":1,"page":0,"per_page":0,"pages_count":0}}`,
+		},
+		{
+			name:    "error in v2 has code and details",
+			res:     response.FromError(apperr.NotFound("course_not_found", "course does not exist").WithDetails(map[string]string{"id": "go"})),
+			status:  http.StatusNotFound,
+			version: "2",
+			lang:    "en",
+			want:    `{"status":404,"error":"course does not exist","code":"course_not_found","details":{"id":"go"}}`,
+		},
+		{
+			name:    "error in v1 has the legacy shape",
+			headers: map[string]string{response.VersionHeader: "v1"},
+			res:     response.FromError(apperr.NotFound("course_not_found", "course does not exist")),
+			status:  http.StatusNotFound,
+			version: "1",
+			lang:    "en",
+			want:    `{"status":404,"error":"course does not exist"}`,
+		},
+		{
+			name:    "invalid version uses the default",
+			headers: map[string]string{response.VersionHeader: "9"},
+			res:     response.OK(nil),
+			status:  http.StatusOK,
+			version: "2",
+			lang:    "en",
+			want:    `{"status":200}`,
+		},
+		{
+			name:    "message in the requested language",
+			headers: map[string]string{"Accept-Language": "es-PE,en;q=0.5"},
+			res:     response.FromError(apperr.Conflict("duplicated", "resource already exists")),
+			status:  http.StatusConflict,
+			version: "2",
+			lang:    "es",
+			want:    `{"status":409,"error":"el recurso ya existe","code":"duplicated"}`,
+		},
+		{
+			name:    "message with arguments from details",
+			headers: map[string]string{"Accept-Language": "es"},
+			res:     response.FromError(apperr.Validation("invalid_date", "invalid date").WithDetails(map[string]string{"field": "start_date"})),
+			status:  http.StatusBadRequest,
+			version: "2",
+			lang:    "es",
+			want:    `{"status":400,"error":"start_date debe tener el formato AAAA-MM-DD","code":"invalid_date","details":{"field":"start_date"}}`,
+		},
+		{
+			name:    "localizable details",
+			headers: map[string]string{"Accept-Language": "es"},
+			res:     response.FromError(apperr.Validation("validation_failed", "request has invalid fields").WithDetails(fieldErrors{"name"})),
+			status:  http.StatusBadRequest,
+			version: "2",
+			lang:    "es",
+			want:    `{"status":400,"error":"la petición tiene campos inválidos","code":"validation_failed","details":["es:name"]}`,
+		},
+		{
+			name:    "unknown code keeps its message",
+			res:     response.FromError(apperr.Conflict("not_in_catalog", "custom message")),
+			status:  http.StatusConflict,
+			version: "2",
+			lang:    "en",
+			want:    `{"status":409,"error":"custom message","code":"not_in_catalog"}`,
+		},
+		{
+			name:    "untyped error is internal",
+			res:     response.FromError(errors.New("connection reset")),
+			status:  http.StatusInternalServerError,
+			version: "2",
+			lang:    "en",
+			want:    `{"status":500,"error":"internal server error","code":"internal_error"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			response.JSON(w, r, tt.res)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get(response.VersionHeader); got != tt.version {
+				t.Errorf("%s = %q, want %q", response.VersionHeader, got, tt.version)
+			}
+			if got := w.Header().Get("Content-Language"); got != tt.lang {
+				t.Errorf("Content-Language = %q, want %q", got, tt.lang)
+			}
+			assertJSON(t, w.Body.Bytes(), tt.want)
+		})
+	}
+}
+
+func TestWriteLegacyAdapter(t *testing.T) {
+	legacy := func(res *response.Response) interface{} {
+		return map[string]interface{}{"course": res.Data}
+	}
+	res := response.OK("go")
+
+	for _, tt := range []struct {
+		version string
+		want    string
+	}{
+		{"1", `{"course":"go"}`},
+		{"2", `{"status":200,"data":"go"}`},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set(response.VersionHeader, tt.version)
+		w := httptest.NewRecorder()
+		response.Write(w, r, res, legacy)
+		assertJSON(t, w.Body.Bytes(), tt.want)
+	}
+}
+
+func TestSetDefaultVersion(t *testing.T) {
+	response.SetDefaultVersion(response.V1)
+	t.Cleanup(func() { response.SetDefaultVersion(response.V2) })
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if got := response.VersionOf(r); got != response.V1 {
+		t.Errorf("VersionOf without header = %d, want 1", got)
+	}
+	r.Header.Set(response.VersionHeader, "2")
+	if got := response.VersionOf(r); got != response.V2 {
+		t.Errorf("VersionOf with header 2 = %d, want 2", got)
+	}
+}
+
+func assertJSON(t *testing.T, body []byte, want string) {
+	t.Helper()
+	var got, exp interface{}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("invalid body %s: %v", body, err)
+	}
+	if err := json.Unmarshal([]byte(want), &exp); err != nil {
+		t.Fatal(err)
+	}
+	g, _ := json.Marshal(got)
+	e, _ := json.Marshal(exp)
+	if string(g) != string(e) {
+		t.Errorf("body = %s, want %s", g, e)
+	}
+}
@@ -0,0 +1,191 @@
+package validate
+
+import (
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/i18n"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError describe una regla que no cumple un campo del request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+
+	// Params son los valores que se interpolan en el mensaje traducido.
+	Params map[string]string `json:"-"`
+}
+
+type FieldErrors []FieldError
+
+// Localize devuelve una copia de los errores con el mensaje en lang.
+func (errs FieldErrors) Localize(lang string) interface{} {
+	localized := make(FieldErrors, len(errs))
+	for i, fe := range errs {
+		args := map[string]string{"field": fe.Field}
+		for name, value := range fe.Params {
+			args[name] = value
+		}
+		if msg, ok := i18n.Message(lang, "field."+fe.Code, args); ok {
+			fe.Message = msg
+		}
+		localized[i] = fe
+	}
+	return localized
+}
+
+var ErrInvalid = apperr.Validation("validation_failed", "request has invalid fields")
+
+// Struct valida v según los tags `validate` de sus campos y devuelve
+// ErrInvalid con todos los campos que fallaron en Details.
+//
+// Reglas: required, min=N, max=N, maxbytes=N, email, digits, date
+// (YYYY-MM-DD) y oneof=a b c. En los strings min y max cuentan caracteres y
+// maxbytes cuenta bytes; en los números, min y max comparan el valor. Los punteros nil se consideran omitidos y no se validan, así los
+// mismos tags sirven para crear y para actualizaciones parciales.
+func Struct(v interface{}) error {
+	errs := Fields(v)
+	if len(errs) == 0 {
+		return nil
+	}
+	return ErrInvalid.WithDetails(errs)
+}
+
+func Fields(v interface{}) FieldErrors {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	var errs FieldErrors
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		rules := strings.Split(tag, ",")
+		// Un campo opcional vacío no se valida con el resto de reglas.
+		if fv.IsZero() && !contains(rules, "required") {
+			continue
+		}
+
+		field := fieldName(sf)
+		for _, rule := range rules {
+			name, param, _ := strings.Cut(rule, "=")
+			if fe := check(field, name, param, fv); fe != nil {
+				errs = append(errs, *fe)
+				break
+			}
+		}
+	}
+	return errs
+}
+
+func check(field, rule, param string, v reflect.Value) *FieldError {
+	fail := func(code string, params map[string]string) *FieldError {
+		args := map[string]string{"field": field}
+		for name, value := range params {
+			args[name] = value
+		}
+		msg, _ := i18n.Message(i18n.EN, "field."+code, args)
+		return &FieldError{Field: field, Code: code, Message: msg, Params: params}
+	}
+
+	switch rule {
+	case "required":
+		if v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+			return fail("required", nil)
+		}
+	case "min":
+		n := atoi(rule, param)
+		if isString(v) && len([]rune(v.String())) < n {
+			return fail("too_short", map[string]string{"n": param})
+		}
+		if isInt(v) && v.Int() < int64(n) {
+			return fail("too_small", map[string]string{"n": param})
+		}
+	case "max":
+		n := atoi(rule, param)
+		if isString(v) && len([]rune(v.String())) > n {
+			return fail("too_long", map[string]string{"n": param})
+		}
+		if isInt(v) && v.Int() > int64(n) {
+			return fail("too_large", map[string]string{"n": param})
+		}
+	case "maxbytes":
+		if n := atoi(rule, param); len(v.String()) > n {
+			return fail("too_many_bytes", map[string]string{"n": param})
+		}
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		if err != nil || addr.Address != v.String() {
+			return fail("invalid_email", nil)
+		}
+	case "digits":
+		if strings.IndexFunc(v.String(), func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+			return fail("invalid_digits", nil)
+		}
+	case "date":
+		if _, err := time.Parse("2006-01-02", v.String()); err != nil {
+			return fail("invalid_date", nil)
+		}
+	case "oneof":
+		options := strings.Fields(param)
+		if !contains(options, v.String()) {
+			return fail("invalid_option", map[string]string{"options": strings.Join(options, ", ")})
+		}
+	default:
+		panic("validate: unknown rule " + rule)
+	}
+	return nil
+}
+
+// fieldName usa el nombre del tag json para que coincida con el del request.
+func fieldName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func atoi(rule, param string) int {
+	n, err := strconv.Atoi(param)
+	if err != nil {
+		panic("validate: invalid parameter for " + rule + ": " + param)
+	}
+	return n
+}
+
+func isString(v reflect.Value) bool {
+	return v.Kind() == reflect.String
+}
+
+func isInt(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
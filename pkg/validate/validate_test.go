@@ -0,0 +1,115 @@
+package validate_test
+
+import (
+	"errors"
+	"github.com/raminpz/gocourse_web/pkg/apperr"
+	"github.com/raminpz/gocourse_web/pkg/validate"
+	"strings"
+	"testing"
+)
+
+type request struct {
+	Name     string  `json:"name" validate:"required,max=5"`
+	Password string  `json:"password" validate:"min=3,maxbytes=6"`
+	Email    string  `json:"email,omitempty" validate:"email"`
+	Phone    *string `json:"phone" validate:"required,digits"`
+	Start    string  `json:"start_date" validate:"date"`
+	Role     string  `json:"role" validate:"oneof=admin student"`
+	Capacity int     `json:"capacity" validate:"min=1,max=10"`
+	Ignored  string  `json:"-" validate:"-"`
+	Untagged string  `validate:"max=1"`
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func valid() request {
+	return request{Name: "Ana", Password: "secret", Email: "ana@example.com", Phone: ptr("123"), Start: "2030-01-31", Role: "admin", Capacity: 5}
+}
+
+func TestFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(r *request)
+		// want es field:code de cada error, en el orden de los campos.
+		want []string
+	}{
+		{name: "valid", change: func(r *request) {}},
+		{name: "optional fields empty", change: func(r *request) { r.Password, r.Email, r.Start, r.Role, r.Capacity = "", "", "", "", 0 }},
+		{name: "required missing", change: func(r *request) { r.Name = "" }, want: []string{"name:required"}},
+		{name: "required blank", change: func(r *request) { r.Name = "   " }, want: []string{"name:required"}},
+		{name: "nil pointer is omitted", change: func(r *request) { r.Phone = nil }},
+		{name: "pointer to empty", change: func(r *request) { r.Phone = ptr("") }, want: []string{"phone:required"}},
+		{name: "max counts characters", change: func(r *request) { r.Name = "ñññññ" }},
+		{name: "too long", change: func(r *request) { r.Name = "Anabel" }, want: []string{"name:too_long"}},
+		{name: "too short", change: func(r *request) { r.Password = "ab" }, want: []string{"password:too_short"}},
+		{name: "maxbytes counts bytes", change: func(r *request) { r.Password = "ñññña" }, want: []string{"password:too_many_bytes"}},
+		{name: "invalid email", change: func(r *request) { r.Email = "Ana <ana@example.com>" }, want: []string{"email:invalid_email"}},
+		{name: "invalid digits", change: func(r *request) { r.Phone = ptr("12a") }, want: []string{"phone:invalid_digits"}},
+		{name: "invalid date", change: func(r *request) { r.Start = "31/01/2030" }, want: []string{"start_date:invalid_date"}},
+		{name: "invalid option", change: func(r *request) { r.Role = "owner" }, want: []string{"role:invalid_option"}},
+		{name: "too small", change: func(r *request) { r.Capacity = -1 }, want: []string{"capacity:too_small"}},
+		{name: "too large", change: func(r *request) { r.Capacity = 11 }, want: []string{"capacity:too_large"}},
+		{name: "ignored field", change: func(r *request) { r.Ignored = "anything" }},
+		{name: "field without json tag", change: func(r *request) { r.Untagged = "ab" }, want: []string{"Untagged:too_long"}},
+		{name: "every failing field", change: func(r *request) { r.Name, r.Role, r.Capacity = "", "owner", 20 },
+			want: []string{"name:required", "role:invalid_option", "capacity:too_large"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := valid()
+			tt.change(&r)
+			var got []string
+			for _, fe := range validate.Fields(&r) {
+				got = append(got, fe.Field+":"+fe.Code)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("Fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStruct(t *testing.T) {
+	r := valid()
+	if err := validate.Struct(r); err != nil {
+		t.Fatalf("Struct(valid) = %v", err)
+	}
+
+	r.Capacity = -1
+	err := validate.Struct(&r)
+	if !errors.Is(err, validate.ErrInvalid) {
+		t.Fatalf("Struct error = %v, want ErrInvalid", err)
+	}
+	errs, _ := apperr.From(err).Details.(validate.FieldErrors)
+	if len(errs) != 1 || errs[0].Message != "capacity must be at least 1" {
+		t.Errorf("details = %+v, want the capacity error in English", errs)
+	}
+}
+
+func TestFieldErrorsLocalize(t *testing.T) {
+	r := valid()
+	r.Name, r.Role = "Anabel", "owner"
+	localized := validate.Fields(&r).Localize("es").(validate.FieldErrors)
+	want := []string{"name debe tener como máximo 5 caracteres", "role debe ser uno de: admin, student"}
+	if len(localized) != len(want) {
+		t.Fatalf("Localize = %+v, want %d errors", localized, len(want))
+	}
+	for i, fe := range localized {
+		if fe.Message != want[i] {
+			t.Errorf("message %d = %q, want %q", i, fe.Message, want[i])
+		}
+	}
+}
+
+func TestUnknownRulePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Fields with an unknown rule did not panic")
+		}
+	}()
+	validate.Fields(struct {
+		Name string `validate:"uppercase"`
+	}{Name: "ana"})
+}
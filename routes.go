@@ -0,0 +1,84 @@
+package main
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/raminpz/gocourse_web/internal/auth"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"github.com/raminpz/gocourse_web/internal/health"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/logging"
+	"github.com/raminpz/gocourse_web/pkg/metrics"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
+	"net/http"
+)
+
+// newRouter arma los servicios sobre store y registra todas las rutas.
+// Devuelve también el servicio de salud para avisarle cuando el servidor drena.
+func newRouter(cfg *config.Config, store *storage, signer *auth.Signer, loggers *logging.Loggers) (*mux.Router, health.Service) {
+	router := mux.NewRouter()
+
+	userLog := loggers.For("user")
+	userSrv := user.NewService(userLog, store.userRepo)
+	userEnd := user.MakeEndpoints(userSrv)
+
+	authLog := loggers.For("auth")
+	authSrv := auth.NewService(store.authRepo, authLog, userSrv, signer, cfg.JWT.AccessTTL, cfg.JWT.RefreshTTL)
+	authEnd := auth.MakeEndpoints(authSrv)
+
+	courseLog := loggers.For("course")
+	courseSrv := course.NewService(store.courseRepo, courseLog, userSrv, store.enrollRepo.Promote)
+	courseEnd := course.MakeEndpoints(courseSrv)
+
+	enrollLog := loggers.For("enrollment")
+	enrollSrv := enrollment.NewService(store.enrollRepo, enrollLog, userSrv, courseSrv)
+	enrollEnd := enrollment.MakeEndpoints(enrollSrv)
+
+	healthSrv := health.NewService(store.db, store.migrator)
+	healthEnd := health.MakeEndpoints(healthSrv)
+
+	// Sondas del orquestador, sin autenticación.
+	router.HandleFunc("/healthz", healthEnd.Live).Methods("GET")
+	router.HandleFunc("/readyz", healthEnd.Ready).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Rutas públicas: registro de usuarios y emisión de tokens.
+	// Cada ruta abre un span que continúa la traza del traceparent recibido.
+	router.Use(otelmux.Middleware(cfg.Tracing.ServiceName))
+
+	router.HandleFunc("/auth/login", authEnd.Login).Methods("POST")
+	router.HandleFunc("/auth/refresh", authEnd.Refresh).Methods("POST")
+	router.HandleFunc("/auth/logout", authEnd.Logout).Methods("POST")
+	router.Handle("/users", auth.OptionalMiddleware(authSrv)(http.HandlerFunc(userEnd.Create))).Methods("POST")
+
+	api := router.PathPrefix("/").Subrouter()
+	api.Use(auth.Middleware(authSrv))
+
+	api.HandleFunc("/users/{id}", userEnd.Get).Methods("GET")
+	api.HandleFunc("/users", userEnd.GetAll).Methods("GET")
+	api.HandleFunc("/users/{id}", userEnd.Update).Methods("PATCH")
+	api.HandleFunc("/users/{id}", userEnd.Delete).Methods("DELETE")
+	api.HandleFunc("/users/{id}/courses-taught", courseEnd.GetTaught).Methods("GET")
+
+	api.HandleFunc("/courses", courseEnd.Create).Methods("POST")
+	api.HandleFunc("/courses/{id}", courseEnd.Get).Methods("GET")
+	api.HandleFunc("/courses", courseEnd.GetAll).Methods("GET")
+	api.HandleFunc("/courses/{id}", courseEnd.Update).Methods("PATCH")
+	api.HandleFunc("/courses/{id}", courseEnd.Delete).Methods("DELETE")
+	api.HandleFunc("/courses/{id}/prerequisites", courseEnd.GetPrerequisites).Methods("GET")
+	api.HandleFunc("/courses/{id}/prerequisites", courseEnd.AddPrerequisite).Methods("POST")
+	api.HandleFunc("/courses/{id}/prerequisites/{prerequisite_id}", courseEnd.RemovePrerequisite).Methods("DELETE")
+	api.HandleFunc("/courses/{id}/instructors", courseEnd.AssignInstructor).Methods("POST")
+	api.HandleFunc("/courses/{id}/instructors/{user_id}", courseEnd.UnassignInstructor).Methods("DELETE")
+	api.HandleFunc("/courses/{id}/waitlist", enrollEnd.Waitlist).Methods("GET")
+
+	api.HandleFunc("/enrollments", enrollEnd.Create).Methods("POST")
+	api.HandleFunc("/enrollments/{id}", enrollEnd.Get).Methods("GET")
+	api.HandleFunc("/enrollments", enrollEnd.GetAll).Methods("GET")
+	api.HandleFunc("/enrollments/{id}", enrollEnd.Update).Methods("PATCH")
+	api.HandleFunc("/enrollments/{id}", enrollEnd.Delete).Methods("DELETE")
+	api.HandleFunc("/enrollments/{id}/transition", enrollEnd.Transition).Methods("POST")
+
+	return router, healthSrv
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/raminpz/gocourse_web/internal/auth"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/logging"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type (
+	// testAPI es el router de la aplicación sobre STORAGE=memory con un
+	// conjunto fijo de datos:
+	//
+	//   - usuarios admin, teacher (instructor), student y other (estudiantes);
+	//   - el curso go, con un lugar y dictado por teacher, y el curso rust, sin
+	//     instructores ni límite de lugares;
+	//   - la inscripción e1 de student en go, que ocupa el lugar, y e2 de other
+	//     en go, en la lista de espera.
+	testAPI struct {
+		router http.Handler
+		store  *storage
+		signer *auth.Signer
+	}
+
+	// apiCase es una petición al router y la respuesta esperada.
+	apiCase struct {
+		name   string
+		as     string // usuario del fixture que hace la petición; vacío es anónimo
+		method string
+		path   string
+		body   string
+		header http.Header
+		status int
+		code   string
+		setup  func(t *testing.T, api *testAPI)
+		check  func(t *testing.T, api *testAPI, res apiResponse)
+	}
+
+	apiResponse struct {
+		Status int             `json:"status"`
+		Data   json.RawMessage `json:"data"`
+		Err    string          `json:"error"`
+		Code   string          `json:"code"`
+		Meta   *struct {
+			TotalCount int `json:"total_count"`
+			Page       int `json:"page"`
+		} `json:"meta"`
+
+		raw []byte
+	}
+)
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	cfg := &config.Config{
+		Storage: config.Storage{Type: "memory"},
+		JWT:     config.JWT{AccessTTL: time.Minute, RefreshTTL: time.Hour},
+	}
+	loggers, err := logging.New(io.Discard, "json", slog.LevelError, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := openStorage(cfg, loggers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := auth.NewHS256Signer([]byte("test-secret-of-at-least-32-bytes!"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, _ := newRouter(cfg, store, signer, loggers)
+	api := &testAPI{router: router, store: store, signer: signer}
+	api.seed(t)
+	return api
+}
+
+func (a *testAPI) seed(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	users := []domain.User{
+		{ID: "admin", Role: domain.RoleAdmin},
+		{ID: "teacher", Role: domain.RoleInstructor},
+		{ID: "student", Role: domain.RoleStudent},
+		{ID: "other", Role: domain.RoleStudent},
+	}
+	for i, u := range users {
+		u.FirstName, u.LastName = strings.ToUpper(u.ID[:1])+u.ID[1:], "López"
+		u.Email = u.ID + "@example.com"
+		u.Phone = string(rune('1' + i))
+		if err := a.store.userRepo.Create(ctx, &u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 1, 0)
+	for _, c := range []domain.Course{
+		{ID: "go", Name: "Go", Capacity: 1},
+		{ID: "rust", Name: "Rust"},
+	} {
+		c.StartDate, c.EndDate = start, start.AddDate(0, 3, 0)
+		if err := a.store.courseRepo.Create(ctx, &c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.store.courseRepo.AddInstructor(ctx, "go", "teacher"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, e := range []domain.Enrollment{
+		{ID: "e1", UserID: "student", CourseID: "go", Status: domain.EnrollmentPending},
+		{ID: "e2", UserID: "other", CourseID: "go", Status: domain.EnrollmentPending},
+	} {
+		if err := a.store.enrollRepo.Create(ctx, &e); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// do envía la petición como el usuario as (vacío es anónimo).
+func (a *testAPI) do(t *testing.T, as, method, path, body string, header http.Header) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if as != "" {
+		token, err := a.signer.Sign(as, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// runAPICases ejecuta cada caso sobre un testAPI nuevo.
+func runAPICases(t *testing.T, tests []apiCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newTestAPI(t)
+			if tt.setup != nil {
+				tt.setup(t, api)
+			}
+			rec := api.do(t, tt.as, tt.method, tt.path, tt.body, tt.header)
+			res := apiResponse{raw: rec.Body.Bytes()}
+			if err := json.Unmarshal(res.raw, &res); err != nil {
+				t.Fatalf("decode %s: %v", rec.Body, err)
+			}
+			if rec.Code != tt.status || res.Code != tt.code {
+				t.Fatalf("%s %s = %d %q, want %d %q; body %s", tt.method, tt.path, rec.Code, res.Code, tt.status, tt.code, rec.Body)
+			}
+			if tt.check != nil {
+				tt.check(t, api, res)
+			}
+		})
+	}
+}
+
+// decodeData decodifica el campo data de res en v.
+func decodeData(t *testing.T, res apiResponse, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(res.Data, v); err != nil {
+		t.Fatalf("decode data %s: %v", res.Data, err)
+	}
+}
+
+// wantLegacyError comprueba que res es un error con la forma de V1: status y
+// error, sin code ni details.
+func wantLegacyError(t *testing.T, _ *testAPI, res apiResponse) {
+	t.Helper()
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(res.raw, &body); err != nil {
+		t.Fatal(err)
+	}
+	_, hasCode := body["code"]
+	_, hasDetails := body["details"]
+	if res.Status == 0 || res.Err == "" || hasCode || hasDetails {
+		t.Errorf("v1 body = %s, want only status and error", res.raw)
+	}
+}
+
+func wantTotal(total int) func(t *testing.T, api *testAPI, res apiResponse) {
+	return func(t *testing.T, _ *testAPI, res apiResponse) {
+		t.Helper()
+		if res.Meta == nil || res.Meta.TotalCount != total {
+			t.Errorf("meta = %+v, want total_count %d", res.Meta, total)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// serve atiende srv en ln hasta que ctx se cancela. Entonces llama a draining,
+// sigue atendiendo durante cfg.ShutdownDelay para que el orquestador vea el
+// cambio en /readyz, deja de aceptar conexiones y espera hasta
+// cfg.ShutdownTimeout a que terminen las peticiones en curso; las que siguen
+// abiertas al vencer el plazo se cortan y se devuelve error.
+func serve(ctx context.Context, srv *http.Server, ln net.Listener, cfg config.Server, draining func(), l *slog.Logger) error {
+	errs := make(chan error, 1)
+	go func() {
+		l.Info("listening", "addr", ln.Addr().String())
+		errs <- srv.Serve(ln)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	draining()
+	if cfg.ShutdownDelay > 0 {
+		l.Info("shutting down after delay", "delay", cfg.ShutdownDelay.String())
+		time.Sleep(cfg.ShutdownDelay)
+	}
+
+	l.Info("shutting down, draining in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	l.Info("server stopped")
+	return nil
+}
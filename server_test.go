@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	tests := []struct {
+		name            string
+		shutdownTimeout time.Duration
+		handlerDelay    time.Duration
+		wantErr         bool
+		wantStatus      int
+	}{
+		{
+			name:            "request finishes within the shutdown timeout",
+			shutdownTimeout: 5 * time.Second,
+			handlerDelay:    300 * time.Millisecond,
+			wantStatus:      http.StatusOK,
+		},
+		{
+			name:            "shutdown timeout shorter than the request",
+			shutdownTimeout: 50 * time.Millisecond,
+			handlerDelay:    5 * time.Second,
+			wantErr:         true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			started := make(chan struct{})
+			srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-time.After(tt.handlerDelay):
+					w.WriteHeader(http.StatusOK)
+				case <-r.Context().Done():
+				}
+			})}
+
+			var draining atomic.Bool
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			cfg := config.Server{ShutdownTimeout: tt.shutdownTimeout}
+			l := slog.New(slog.NewTextHandler(io.Discard, nil))
+			served := make(chan error, 1)
+			go func() {
+				served <- serve(ctx, srv, ln, cfg, func() { draining.Store(true) }, l)
+			}()
+
+			type result struct {
+				status int
+				err    error
+			}
+			responses := make(chan result, 1)
+			go func() {
+				resp, err := http.Get("http://" + ln.Addr().String())
+				if err != nil {
+					responses <- result{err: err}
+					return
+				}
+				resp.Body.Close()
+				responses <- result{status: resp.StatusCode}
+			}()
+
+			select {
+			case <-started:
+			case <-time.After(5 * time.Second):
+				t.Fatal("request never reached the handler")
+			}
+			cancel()
+
+			var serveErr error
+			select {
+			case serveErr = <-served:
+			case <-time.After(10 * time.Second):
+				t.Fatal("serve did not return")
+			}
+			if got := serveErr != nil; got != tt.wantErr {
+				t.Fatalf("serve error = %v, want error %v", serveErr, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(serveErr, context.DeadlineExceeded) {
+				t.Errorf("serve error = %v, want context.DeadlineExceeded", serveErr)
+			}
+			if !draining.Load() {
+				t.Error("draining was not called")
+			}
+
+			res := <-responses
+			if tt.wantErr {
+				if res.err == nil {
+					t.Errorf("request got status %d, want the connection cut", res.status)
+				}
+				return
+			}
+			if res.err != nil || res.status != tt.wantStatus {
+				t.Errorf("request got status %d (%v), want %d", res.status, res.err, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/auth"
+	"github.com/raminpz/gocourse_web/internal/course"
+	"github.com/raminpz/gocourse_web/internal/enrollment"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"github.com/raminpz/gocourse_web/pkg/bootstrap"
+	"github.com/raminpz/gocourse_web/pkg/config"
+	"github.com/raminpz/gocourse_web/pkg/logging"
+	"github.com/raminpz/gocourse_web/pkg/migrate"
+	"gorm.io/gorm"
+)
+
+// storage son los repositorios de cada paquete. Con STORAGE=memory db y
+// migrator quedan en nil.
+type storage struct {
+	db         *gorm.DB
+	migrator   *migrate.Migrator
+	userRepo   user.Repository
+	authRepo   auth.Repository
+	courseRepo course.Repository
+	enrollRepo enrollment.Repository
+}
+
+func openStorage(cfg *config.Config, loggers *logging.Loggers) (*storage, error) {
+	if cfg.Storage.Memory() {
+		userRepo := user.NewMemoryRepo(loggers.For("user"))
+		courseRepo := course.NewMemoryRepo(loggers.For("course"), userRepo)
+		return &storage{
+			userRepo:   userRepo,
+			authRepo:   auth.NewMemoryRepo(loggers.For("auth")),
+			courseRepo: courseRepo,
+			enrollRepo: enrollment.NewMemoryRepo(loggers.For("enrollment"), userRepo, courseRepo),
+		}, nil
+	}
+
+	db, err := bootstrap.DBConnection(cfg.Database, loggers.For("migrate"))
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	migrator, err := bootstrap.Migrator(db, loggers.For("migrate"))
+	if err != nil {
+		return nil, fmt.Errorf("load migrations: %w", err)
+	}
+	return &storage{
+		db:         db,
+		migrator:   migrator,
+		userRepo:   user.NewRepo(loggers.For("user"), db),
+		authRepo:   auth.NewRepo(db, loggers.For("auth")),
+		courseRepo: course.NewRepo(db, loggers.For("course")),
+		enrollRepo: enrollment.NewRepo(db, loggers.For("enrollment")),
+	}, nil
+}
+
+// Close cierra la base de datos, si hay una.
+func (s *storage) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return bootstrap.CloseDB(s.db)
+}
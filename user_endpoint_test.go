@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/raminpz/gocourse_web/internal/domain"
+	"github.com/raminpz/gocourse_web/internal/user"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const newUser = `{"first_name":"Nora","last_name":"Díaz","email":"nora@example.com","phone":"555","password":"secret-pass"%s}`
+
+func TestUserCreate(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "visitor registers as student", method: "POST", path: "/users",
+			body: fmt.Sprintf(newUser, ""), status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				var u domain.User
+				decodeData(t, res, &u)
+				if u.Role != domain.RoleStudent || u.Email != "nora@example.com" || u.ID == "" {
+					t.Errorf("created user = %+v", u)
+				}
+				if _, err := api.store.userRepo.GetByEmail(context.Background(), "nora@example.com"); err != nil {
+					t.Errorf("user was not stored: %v", err)
+				}
+			},
+		},
+		{
+			name: "admin assigns a role", as: "admin", method: "POST", path: "/users",
+			body: fmt.Sprintf(newUser, `,"role":"instructor"`), status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var u domain.User
+				decodeData(t, res, &u)
+				if u.Role != domain.RoleInstructor {
+					t.Errorf("role = %q, want instructor", u.Role)
+				}
+			},
+		},
+		{
+			name: "visitor cannot choose a role", method: "POST", path: "/users",
+			body: fmt.Sprintf(newUser, `,"role":"admin"`), status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "student cannot choose a role", as: "student", method: "POST", path: "/users",
+			body: fmt.Sprintf(newUser, `,"role":"instructor"`), status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "malformed body", method: "POST", path: "/users",
+			body: `{`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "invalid fields", method: "POST", path: "/users",
+			body: `{"first_name":"Nora","email":"not-an-email","phone":"abc","password":"short"}`, status: http.StatusBadRequest, code: "validation_failed",
+		},
+		{
+			name: "password over 72 bytes", method: "POST", path: "/users",
+			body:   fmt.Sprintf(`{"first_name":"Nora","email":"nora@example.com","password":"%s"}`, strings.Repeat("ñ", 40)),
+			status: http.StatusBadRequest, code: "validation_failed",
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				if !strings.Contains(string(res.raw), `"code":"too_many_bytes"`) {
+					t.Errorf("body = %s, want a too_many_bytes field error", res.raw)
+				}
+			},
+		},
+		{
+			name: "email already used", method: "POST", path: "/users",
+			body:   `{"first_name":"Nora","last_name":"Díaz","email":"student@example.com","phone":"555","password":"secret-pass"}`,
+			status: http.StatusConflict, code: "duplicated",
+		},
+	})
+}
+
+func TestUserGet(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "own profile", as: "student", method: "GET", path: "/users/student", status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				var u domain.User
+				decodeData(t, res, &u)
+				if u.ID != "student" || u.Email != "student@example.com" {
+					t.Errorf("user = %+v", u)
+				}
+			},
+		},
+		{
+			name: "admin reads anyone", as: "admin", method: "GET", path: "/users/other", status: http.StatusOK,
+		},
+		{
+			name: "v1 returns the bare user", as: "student", method: "GET", path: "/users/student",
+			header: http.Header{"Api-Version": {"1"}}, status: http.StatusOK,
+			check: func(t *testing.T, _ *testAPI, res apiResponse) {
+				if res.Data != nil || res.Status != 0 {
+					t.Errorf("v1 body has an envelope: %s", res.raw)
+				}
+			},
+		},
+		{
+			name: "another user's profile", as: "student", method: "GET", path: "/users/other", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "missing user", as: "admin", method: "GET", path: "/users/missing", status: http.StatusNotFound, code: "user_not_found",
+		},
+		{
+			name: "v1 error format", as: "admin", method: "GET", path: "/users/missing",
+			header: http.Header{"Api-Version": {"1"}}, status: http.StatusNotFound, check: wantLegacyError,
+		},
+		{
+			name: "without a token", method: "GET", path: "/users/student", status: http.StatusUnauthorized, code: "token_required",
+		},
+		{
+			name: "with an invalid token", method: "GET", path: "/users/student",
+			header: http.Header{"Authorization": {"Bearer nope"}}, status: http.StatusUnauthorized, code: "invalid_token",
+		},
+	})
+}
+
+func TestUserGetAll(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "admin lists everyone", as: "admin", method: "GET", path: "/users", status: http.StatusOK,
+			check: wantTotal(4),
+		},
+		{
+			name: "filter by first name", as: "admin", method: "GET", path: "/users?first_name=stu", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				wantTotal(1)(t, api, res)
+				var users []domain.User
+				decodeData(t, res, &users)
+				if len(users) != 1 || users[0].ID != "student" {
+					t.Errorf("users = %+v, want only student", users)
+				}
+			},
+		},
+		{
+			name: "second page", as: "admin", method: "GET", path: "/users?limit=3&page=2", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, res apiResponse) {
+				var users []domain.User
+				decodeData(t, res, &users)
+				if len(users) != 1 || res.Meta.Page != 2 {
+					t.Errorf("page %d has %d users, want page 2 with 1", res.Meta.Page, len(users))
+				}
+			},
+		},
+		{
+			name: "students cannot list users", as: "student", method: "GET", path: "/users", status: http.StatusForbidden, code: "forbidden",
+		},
+	})
+}
+
+func TestUserUpdate(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "own profile", as: "student", method: "PATCH", path: "/users/student",
+			body: `{"first_name":"Estela","locale":"es"}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				u, _ := api.store.userRepo.GetByID(context.Background(), "student")
+				if u.FirstName != "Estela" || u.Locale != "es" || u.LastName != "López" {
+					t.Errorf("after update user = %+v", u)
+				}
+			},
+		},
+		{
+			name: "admin changes a role", as: "admin", method: "PATCH", path: "/users/student",
+			body: `{"role":"instructor"}`, status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if u, _ := api.store.userRepo.GetByID(context.Background(), "student"); u.Role != domain.RoleInstructor {
+					t.Errorf("role = %q, want instructor", u.Role)
+				}
+			},
+		},
+		{
+			name: "role is not kept when the rest fails", as: "admin", method: "PATCH", path: "/users/student",
+			body: `{"role":"instructor","email":"other@example.com"}`, status: http.StatusConflict, code: "duplicated",
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if u, _ := api.store.userRepo.GetByID(context.Background(), "student"); u.Role != domain.RoleStudent {
+					t.Errorf("role = %q, want student", u.Role)
+				}
+			},
+		},
+		{
+			name: "student cannot change their role", as: "student", method: "PATCH", path: "/users/student",
+			body: `{"role":"admin"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "another user's profile", as: "student", method: "PATCH", path: "/users/other",
+			body: `{"first_name":"Otro"}`, status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "password without the current one", as: "student", method: "PATCH", path: "/users/student",
+			body: `{"password":"new-secret"}`, status: http.StatusBadRequest, code: "current_password_required",
+		},
+		{
+			name: "invalid fields", as: "student", method: "PATCH", path: "/users/student",
+			body: `{"email":"nope","locale":"fr"}`, status: http.StatusBadRequest, code: "validation_failed",
+		},
+		{
+			name: "malformed body", as: "student", method: "PATCH", path: "/users/student",
+			body: `[`, status: http.StatusBadRequest, code: "invalid_request",
+		},
+		{
+			name: "email taken", as: "student", method: "PATCH", path: "/users/student",
+			body: `{"email":"other@example.com"}`, status: http.StatusConflict, code: "duplicated",
+		},
+		{
+			name: "missing user", as: "admin", method: "PATCH", path: "/users/missing",
+			body: `{"first_name":"Nadie"}`, status: http.StatusNotFound, code: "user_not_found",
+		},
+	})
+}
+
+func TestUserDelete(t *testing.T) {
+	runAPICases(t, []apiCase{
+		{
+			name: "admin deletes a user", as: "admin", method: "DELETE", path: "/users/other", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if _, err := api.store.userRepo.GetByID(context.Background(), "other"); !errors.Is(err, user.ErrNotFound) {
+					t.Errorf("GetByID after delete error = %v, want ErrNotFound", err)
+				}
+			},
+		},
+		{
+			name: "students cannot delete users", as: "student", method: "DELETE", path: "/users/student", status: http.StatusForbidden, code: "forbidden",
+		},
+		{
+			name: "missing user", as: "admin", method: "DELETE", path: "/users/missing", status: http.StatusNotFound, code: "user_not_found",
+		},
+		{
+			name: "deleted user's token stops working", as: "admin", method: "DELETE", path: "/users/student", status: http.StatusOK,
+			check: func(t *testing.T, api *testAPI, _ apiResponse) {
+				if rec := api.do(t, "student", "GET", "/users/student", "", nil); rec.Code != http.StatusUnauthorized {
+					t.Errorf("request with a deleted user's token = %d, want 401", rec.Code)
+				}
+			},
+		},
+	})
+}